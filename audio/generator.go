@@ -0,0 +1,204 @@
+package audio
+
+import (
+	"chip8emu/core"
+	"chip8emu/opts"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+)
+
+//SampleRate the rate samples are generated at, in samples per second
+const SampleRate = 44100
+
+//fadeTime the time the volume takes to ramp up when the tone starts and down when
+//it stops, in seconds. Starting or stopping a wave part way through a cycle
+//otherwise clicks.
+const fadeTime = 0.002
+
+//Waveform the shape of the tone
+type Waveform int
+
+//Waveforms
+const (
+	Square Waveform = iota
+	Sine
+)
+
+var waveforms = map[string]Waveform{"square": Square, "sine": Sine}
+
+//ParseWaveform the waveform with the given name, square or sine
+func ParseWaveform(name string) (Waveform, error) {
+	w, exists := waveforms[strings.ToLower(name)]
+	if !exists {
+		return Square, fmt.Errorf("unknown waveform %q, expected square or sine", name)
+	}
+	return w, nil
+}
+
+//Tone the sound of the beep
+type Tone struct {
+	Waveform Waveform
+	//Frequency in Hz
+	Frequency float64
+	//Volume from 0 to 1
+	Volume float64
+}
+
+//DefaultTone the beep when no options are given
+func DefaultTone() Tone {
+	return Tone{Waveform: Square, Frequency: 440, Volume: 0.25}
+}
+
+//NewTone the beep given by the run options
+func NewTone(o *opts.Opts) (Tone, error) {
+	t := DefaultTone()
+	var err error
+	if o.Waveform != "" {
+		if t.Waveform, err = ParseWaveform(o.Waveform); err != nil {
+			return t, err
+		}
+	}
+	if o.ToneHz != 0 {
+		if o.ToneHz < 20 || o.ToneHz > SampleRate/2 {
+			return t, fmt.Errorf("tone frequency %vHz out of range, expected 20-%d", o.ToneHz, SampleRate/2)
+		}
+		t.Frequency = o.ToneHz
+	}
+	if o.Volume < 0 || o.Volume > 100 {
+		return t, fmt.Errorf("volume %d out of range, expected 0-100", o.Volume)
+	}
+	t.Volume = float64(o.Volume) / 100
+	return t, nil
+}
+
+//Generator generates the machine's beep as 16 bit signed samples at SampleRate.
+//The tone plays between Play and Stop, which may be called from a different
+//goroutine to Render. Each beep starts at the beginning of the wave and fades in
+//and out, so beeps are click free and the same every time.
+type Generator struct {
+	tone Tone
+
+	mu      sync.Mutex
+	playing bool
+	//pattern the XO-CHIP audio pattern to play rather than the tone, if set, at
+	//patternRate bits per second
+	pattern     *[16]uint8
+	patternRate float64
+	//phase the position in the wave's cycle from 0 to 1, or the pattern bit
+	phase float64
+	//level the volume envelope from 0 to 1, ramping towards 1 while playing
+	level float64
+}
+
+//NewGenerator constructor for a generator of the given tone, initially silent
+func NewGenerator(t Tone) *Generator {
+	return &Generator{tone: t}
+}
+
+//Play starts the beep, or keeps it playing
+func (g *Generator) Play() {
+	g.mu.Lock()
+	g.playing = true
+	g.mu.Unlock()
+}
+
+//Stop stops the beep, fading out
+func (g *Generator) Stop() {
+	g.mu.Lock()
+	g.playing = false
+	g.mu.Unlock()
+}
+
+//Playing reports whether the beep is playing, i.e. between Play and Stop
+func (g *Generator) Playing() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.playing
+}
+
+//SetPattern plays an XO-CHIP audio pattern instead of the tone, one bit per step
+//at rate steps per second
+func (g *Generator) SetPattern(pattern [16]uint8, rate float64) {
+	g.mu.Lock()
+	g.pattern, g.patternRate = &pattern, rate
+	g.mu.Unlock()
+}
+
+//Render fills buf with the next samples
+func (g *Generator) Render(buf []int16) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	fade := 1 / (fadeTime * SampleRate)
+	amplitude := g.tone.Volume * math.MaxInt16
+	for i := range buf {
+		if g.playing {
+			g.level = math.Min(g.level+fade, 1)
+		} else {
+			g.level = math.Max(g.level-fade, 0)
+		}
+		if g.level == 0 {
+			g.phase = 0
+			buf[i] = 0
+			continue
+		}
+		buf[i] = int16(g.sample() * g.level * amplitude)
+		g.advance()
+	}
+}
+
+//sample the value of the wave at the current phase, from -1 to 1
+func (g *Generator) sample() float64 {
+	if g.pattern != nil {
+		bit := int(g.phase)
+		if g.pattern[bit/8]&(0x80>>uint(bit%8)) != 0 {
+			return 1
+		}
+		return -1
+	}
+	if g.tone.Waveform == Sine {
+		return math.Sin(2 * math.Pi * g.phase)
+	}
+	if g.phase < 0.5 {
+		return 1
+	}
+	return -1
+}
+
+//advance moves the phase on by one sample
+func (g *Generator) advance() {
+	if g.pattern != nil {
+		g.phase += g.patternRate / SampleRate
+		if bits := float64(len(g.pattern) * 8); g.phase >= bits {
+			g.phase -= bits
+		}
+		return
+	}
+	g.phase += g.tone.Frequency / SampleRate
+	if g.phase >= 1 {
+		g.phase--
+	}
+}
+
+//Output plays the machine's beep through a generator, as its audio handler. The
+//XO-CHIP audio pattern, once a program has loaded one, replaces the tone and is
+//picked up every frame the sound timer is active.
+type Output struct {
+	*Generator
+	cpu *core.Chip8
+}
+
+//NewOutput constructor for an output of the machine's beep through g
+func NewOutput(cpu *core.Chip8, g *Generator) *Output {
+	return &Output{Generator: g, cpu: cpu}
+}
+
+//Play starts or keeps playing the beep, with the current audio pattern
+func (o *Output) Play() {
+	if o.cpu.PatternLoaded {
+		o.SetPattern(o.cpu.AudioPattern, o.cpu.PatternRate())
+	}
+	o.Generator.Play()
+}
@@ -0,0 +1,159 @@
+package audio
+
+import (
+	"chip8emu/core"
+	"chip8emu/opts"
+	"fmt"
+	"math"
+	"testing"
+)
+
+//crossings the number of times the samples change sign
+func crossings(buf []int16) int {
+	n := 0
+	for i := 1; i < len(buf); i++ {
+		if (buf[i-1] < 0) != (buf[i] < 0) {
+			n++
+		}
+	}
+	return n
+}
+
+//maxStep the largest change between neighbouring samples
+func maxStep(buf []int16) int {
+	max := 0
+	for i := 1; i < len(buf); i++ {
+		if step := int(buf[i]) - int(buf[i-1]); step > max {
+			max = step
+		} else if -step > max {
+			max = -step
+		}
+	}
+	return max
+}
+
+func TestGeneratorTone(t *testing.T) {
+	for _, tone := range []Tone{{Square, 441, 0.5}, {Sine, 441, 0.5}} {
+		g := NewGenerator(tone)
+		buf := make([]int16, SampleRate/10)
+		g.Render(buf)
+		if crossings(buf) == 0 && buf[0] == 0 && buf[len(buf)-1] == 0 {
+			t.Log("Silent until played - Test Passed")
+		} else {
+			t.Error("Expected silence before Play, actual ", buf[:8])
+		}
+
+		g.Play()
+		g.Render(buf)
+		//441Hz for a tenth of a second is 44.1 cycles, each crossing zero twice
+		if n := crossings(buf); n >= 87 && n <= 89 {
+			t.Log("Tone at the given frequency - Test Passed")
+		} else {
+			msg := fmt.Sprintf("Expected 88 zero crossings, actual %d", n)
+			t.Error(msg)
+		}
+
+		peak := 0
+		for _, s := range buf {
+			if int(s) > peak {
+				peak = int(s)
+			}
+		}
+		if want := math.MaxInt16 / 2; peak >= want && peak <= want+1 {
+			t.Log("Tone at the given volume - Test Passed")
+		} else {
+			msg := fmt.Sprintf("Expected a peak of %d, actual %d", want, peak)
+			t.Error(msg)
+		}
+
+		g.Stop()
+		g.Render(buf)
+		if buf[len(buf)-1] == 0 && buf[SampleRate/100] == 0 {
+			t.Log("Silent after stopping - Test Passed")
+		} else {
+			t.Error("Expected silence after Stop, actual ", buf[len(buf)-8:])
+		}
+	}
+}
+
+func TestGeneratorClickFree(t *testing.T) {
+	g := NewGenerator(Tone{Sine, 440, 1})
+	buf := make([]int16, 1000)
+	g.Play()
+	g.Render(buf[:500])
+	g.Stop()
+	g.Render(buf[500:])
+
+	//A full volume 440Hz sine changes by at most about 2050 a sample, starting or
+	//stopping abruptly would jump by up to the full amplitude
+	start, stop := maxStep(buf[:20]), maxStep(buf[490:600])
+	if limit := 2100; start < limit && stop < limit {
+		t.Log("Beep fades in and out - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected steps below %d starting and stopping, actual %d and %d", limit, start, stop)
+		t.Error(msg)
+	}
+}
+
+func TestGeneratorPattern(t *testing.T) {
+	g := NewGenerator(DefaultTone())
+	var pattern [16]uint8
+	pattern[0] = 0xF0
+	//One bit every 8 samples
+	g.SetPattern(pattern, SampleRate/8.0)
+	g.Play()
+	buf := make([]int16, 1024*2)
+	g.Render(buf)
+
+	//After fading in: 32 high samples, then the rest of the 128 bit pattern low
+	second := buf[1024:]
+	if second[0] > 0 && second[31] > 0 && second[32] < 0 && second[1023] < 0 {
+		t.Log("Audio pattern played - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected pattern samples %v %v", second[:8], second[28:36])
+		t.Error(msg)
+	}
+}
+
+func TestOutputFollowsSoundTimer(t *testing.T) {
+	chip := core.NewChip8()
+	core.NewHeadlessDisplay(chip)
+	out := NewOutput(chip, NewGenerator(DefaultTone()))
+	chip.AudioHandler = out
+	chip.SetCyclesPerFrame(4)
+	chip.SetMem(0x200, core.LoadVxFromKk|0x002) //V0 = 2
+	chip.SetMem(0x202, core.LoadSoundTimerFromVx)
+	chip.SetMem(0x204, core.Jump|0x204)
+
+	chip.RunCycles(2)
+	playing := out.Playing()
+	chip.RunCycles(2) //First tick
+	stillPlaying := out.Playing()
+	chip.RunFrames(1) //Second tick
+
+	if playing && stillPlaying && !out.Playing() {
+		t.Log("Beep plays while the sound timer is active - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected the beep to start on FX18 and stop after 2 frames, actual %v, %v, %v", playing, stillPlaying, out.Playing())
+		t.Error(msg)
+	}
+}
+
+func TestNewTone(t *testing.T) {
+	tone, err := NewTone(&opts.Opts{Waveform: "Sine", ToneHz: 300, Volume: 50})
+	if err == nil && tone == (Tone{Sine, 300, 0.5}) {
+		t.Log("Tone from options - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected tone %+v, error %v", tone, err)
+		t.Error(msg)
+	}
+
+	for _, o := range []opts.Opts{{Waveform: "saw"}, {ToneHz: 30000}, {Volume: 101}} {
+		if _, err := NewTone(&o); err != nil {
+			t.Log("Invalid option rejected: ", err, " - Test Passed")
+		} else {
+			msg := fmt.Sprintf("Expected an error for %+v", o)
+			t.Error(msg)
+		}
+	}
+}
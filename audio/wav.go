@@ -0,0 +1,141 @@
+package audio
+
+import (
+	"bufio"
+	"chip8emu/core"
+	"encoding/binary"
+	"io"
+	"os"
+)
+
+//SamplesPerFrame the number of samples making up a 60Hz frame
+const SamplesPerFrame = SampleRate / core.FrameRate
+
+//wavHeaderSize the size of the RIFF and format headers preceding the samples
+const wavHeaderSize = 44
+
+//WAVRecorder records the machine's beep to a 16 bit mono WAV file at SampleRate,
+//as its audio handler. The machine is run a frame at a time, with AddFrame called
+//after each frame to record its audio. A frame is heard if the sound timer was
+//active at its start or was set during it, so the recording doesn't depend on
+//where in the frame the timer was set and is the same on every run.
+type WAVRecorder struct {
+	out  *Output
+	file *os.File
+	w    *bufio.Writer
+	buf  []int16
+
+	//active whether the sound timer is active, played whether it was set during
+	//the current frame, and heard whether it was active at the start of the frame
+	active, played, heard bool
+
+	samples int
+	err     error
+}
+
+//NewWAVRecorder creates the WAV file at path and attaches the recorder to the
+//machine as its audio handler. The file is complete once Close is called.
+func NewWAVRecorder(cpu *core.Chip8, path string, t Tone) (*WAVRecorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &WAVRecorder{
+		out:  NewOutput(cpu, NewGenerator(t)),
+		file: f,
+		w:    bufio.NewWriter(f),
+		buf:  make([]int16, SamplesPerFrame),
+	}
+	//Sizes are filled in by Close
+	r.err = r.writeHeader(0)
+	cpu.AudioHandler = r
+	return r, nil
+}
+
+//writeHeader writes the RIFF and format headers for a file holding the given
+//number of samples
+func (r *WAVRecorder) writeHeader(samples int) error {
+	const channels, bits = 1, 16
+	header := struct {
+		RIFF          [4]byte
+		Size          uint32
+		WAVE          [4]byte
+		Fmt           [4]byte
+		FmtSize       uint32
+		Format        uint16
+		Channels      uint16
+		SampleRate    uint32
+		ByteRate      uint32
+		BlockAlign    uint16
+		BitsPerSample uint16
+		Data          [4]byte
+		DataSize      uint32
+	}{
+		RIFF:          [4]byte{'R', 'I', 'F', 'F'},
+		Size:          uint32(wavHeaderSize - 8 + samples*2),
+		WAVE:          [4]byte{'W', 'A', 'V', 'E'},
+		Fmt:           [4]byte{'f', 'm', 't', ' '},
+		FmtSize:       16,
+		Format:        1, //PCM
+		Channels:      channels,
+		SampleRate:    SampleRate,
+		ByteRate:      SampleRate * channels * bits / 8,
+		BlockAlign:    channels * bits / 8,
+		BitsPerSample: bits,
+		Data:          [4]byte{'d', 'a', 't', 'a'},
+		DataSize:      uint32(samples * 2),
+	}
+	return binary.Write(r.w, binary.LittleEndian, &header)
+}
+
+//Play notes that the sound timer is active
+func (r *WAVRecorder) Play() {
+	r.active, r.played = true, true
+}
+
+//Stop notes that the sound timer has reached zero
+func (r *WAVRecorder) Stop() {
+	r.active = false
+}
+
+//AddFrame records the audio of the frame just run
+func (r *WAVRecorder) AddFrame() {
+	if r.played || r.heard {
+		r.out.Play()
+	} else {
+		r.out.Stop()
+	}
+	r.heard, r.played = r.active, false
+
+	r.out.Render(r.buf)
+	r.samples += len(r.buf)
+	if r.err == nil {
+		r.err = binary.Write(r.w, binary.LittleEndian, r.buf)
+	}
+}
+
+//Samples the number of samples recorded
+func (r *WAVRecorder) Samples() int {
+	return r.samples
+}
+
+//Close completes the WAV file. The recorder remains the machine's audio handler,
+//discarding the sound.
+func (r *WAVRecorder) Close() error {
+	if r.err == nil {
+		r.err = r.w.Flush()
+	}
+	if r.err == nil {
+		//Rewrite the header now the sizes are known
+		if _, r.err = r.file.Seek(0, io.SeekStart); r.err == nil {
+			r.err = r.writeHeader(r.samples)
+		}
+		if r.err == nil {
+			r.err = r.w.Flush()
+		}
+	}
+	if err := r.file.Close(); r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
@@ -0,0 +1,81 @@
+package audio
+
+import (
+	"bytes"
+	"chip8emu/core"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+//heardFrames which frames of a recording have any sound
+func heardFrames(samples []int16) []bool {
+	var heard []bool
+	for f := 0; f+SamplesPerFrame <= len(samples); f += SamplesPerFrame {
+		loud := false
+		for _, s := range samples[f : f+SamplesPerFrame] {
+			loud = loud || s != 0
+		}
+		heard = append(heard, loud)
+	}
+	return heard
+}
+
+func TestWAVRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "beep.wav")
+
+	chip := core.NewChip8()
+	core.NewHeadlessDisplay(chip)
+	chip.SetCyclesPerFrame(2)
+	chip.SetMem(0x200, core.LoadVxFromKk|0x002) //V0 = 2
+	chip.SetMem(0x202, core.Jump|0x206)
+	chip.SetMem(0x206, core.LoadSoundTimerFromVx) //in the second frame
+	chip.SetMem(0x208, core.Jump|0x208)
+
+	wav, err := NewWAVRecorder(chip, path, DefaultTone())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 6; i++ {
+		chip.RunFrames(1)
+		wav.AddFrame()
+	}
+	if err := wav.Close(); err != nil {
+		t.Fatal("Close failed: ", err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	le := binary.LittleEndian
+	if len(data) == wavHeaderSize+6*SamplesPerFrame*2 && string(data[:4]) == "RIFF" && string(data[8:16]) == "WAVEfmt " &&
+		le.Uint32(data[4:]) == uint32(len(data)-8) && le.Uint32(data[24:]) == SampleRate && le.Uint16(data[34:]) == 16 &&
+		le.Uint32(data[40:]) == 6*SamplesPerFrame*2 {
+		t.Log("WAV header - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected WAV file of %d bytes, header % X", len(data), data[:wavHeaderSize])
+		t.Error(msg)
+	}
+
+	samples := make([]int16, (len(data)-wavHeaderSize)/2)
+	binary.Read(bytes.NewReader(data[wavHeaderSize:]), le, samples)
+	//Heard in the frame the timer was set and the next, then fading out over the
+	//start of the one after
+	heard := heardFrames(samples)
+	expected := []bool{false, true, true, true, false, false}
+	if fmt.Sprint(heard) == fmt.Sprint(expected) && samples[3*SamplesPerFrame+SamplesPerFrame/2] == 0 {
+		t.Log("Beep recorded for the frames the sound timer was active - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected sound in frames %v, actual %v", expected, heard)
+		t.Error(msg)
+	}
+}
@@ -0,0 +1,69 @@
+package backend
+
+import (
+	"chip8emu/core"
+	"chip8emu/opts"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//Backend a front end providing the machine's display, input and audio, attached
+//to the machine by its Factory
+type Backend interface {
+	//Shutdown releases the front end once the machine has stopped
+	Shutdown()
+}
+
+//Factory creates a backend for the machine, setting its display, input, audio
+//and clock handlers as needed. Called on the goroutine that runs the machine.
+type Factory func(cpu *core.Chip8, opts *opts.Opts) (Backend, error)
+
+var factories = map[string]Factory{}
+
+//Register makes a backend available by name, called from the init function of
+//the package implementing it
+func Register(name string, f Factory) {
+	if _, exists := factories[name]; exists {
+		panic("backend " + name + " registered twice")
+	}
+	factories[name] = f
+}
+
+//Names the registered backends, sorted
+func Names() []string {
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//New creates the named backend for the machine
+func New(name string, cpu *core.Chip8, opts *opts.Opts) (Backend, error) {
+	f, exists := factories[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown backend %q, expected one of: %s", name, strings.Join(Names(), ", "))
+	}
+	return f(cpu, opts)
+}
+
+func init() {
+	Register("null", newNull)
+}
+
+//null a backend with no display, input or audio, running at normal speed until
+//the program exits or the process is interrupted. Useful with --trace or the
+//debugger.
+type null struct{}
+
+func newNull(cpu *core.Chip8, opts *opts.Opts) (Backend, error) {
+	clock := cpu.Clock
+	core.NewHeadlessDisplay(cpu)
+	cpu.Clock = clock
+	return null{}, nil
+}
+
+//Shutdown does nothing
+func (null) Shutdown() {}
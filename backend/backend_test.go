@@ -0,0 +1,26 @@
+package backend
+
+import (
+	"chip8emu/core"
+	"chip8emu/opts"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	chip := core.NewChip8()
+	b, err := New("null", chip, &opts.Opts{})
+	if err == nil && b != nil && chip.DisplayHandler.IsAlive() {
+		t.Log("null backend created - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected a null backend, actual %v error %v", b, err)
+		t.Error(msg)
+	}
+
+	if _, err := New("bogus", chip, &opts.Opts{}); err != nil && strings.Contains(err.Error(), "null") {
+		t.Log("Unknown backend rejected - Test Passed")
+	} else {
+		t.Error("Expected an error listing the backends, actual ", err)
+	}
+}
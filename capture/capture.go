@@ -0,0 +1,103 @@
+package capture
+
+import (
+	"chip8emu/core"
+	"chip8emu/opts"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+)
+
+//Default size of a low resolution pixel, in image pixels
+const (
+	DefaultXSize = 10
+	DefaultYSize = 10
+)
+
+//DefaultPalette the colours of the XO-CHIP plane combinations as 0xAARRGGBB, index
+//0 is the background
+var DefaultPalette = [4]uint32{
+	0xff000000,
+	0xffffffff,
+	0xffaaaaaa,
+	0xff555555,
+}
+
+//Options how the display is drawn to an image
+type Options struct {
+	//XSize and YSize the size of a low resolution pixel, high resolution pixels are
+	//drawn at half size so the image size doesn't change. With an odd size they
+	//alternate between sizes rounded down and up, with size 1 only every other
+	//one is drawn.
+	XSize, YSize int
+	Palette      [4]uint32
+}
+
+//NewOptions the options matching the SDL window for the run options, i.e. the same
+//pixel size and background colour
+func NewOptions(o *opts.Opts) Options {
+	options := Options{XSize: o.XSize, YSize: o.YSize, Palette: DefaultPalette}
+	options.Palette[0] = o.BgColour
+	if options.XSize <= 0 {
+		options.XSize = DefaultXSize
+	}
+	if options.YSize <= 0 {
+		options.YSize = DefaultYSize
+	}
+	return options
+}
+
+//DefaultOptions the options for the default window size and colours
+func DefaultOptions() Options {
+	return NewOptions(&opts.Opts{})
+}
+
+//colorPalette the palette as colours, always opaque as the background colour
+//option is usually given without an alpha channel
+func (o Options) colorPalette() color.Palette {
+	p := make(color.Palette, len(o.Palette))
+	for i, c := range o.Palette {
+		p[i] = color.RGBA{R: uint8(c >> 16), G: uint8(c >> 8), B: uint8(c), A: 0xff}
+	}
+	return p
+}
+
+//Frame draws the display as a paletted image, with the palette index of each pixel
+//being its XO-CHIP plane combination
+func Frame(cpu *core.Chip8, o Options) *image.Paletted {
+	width, height := 64*o.XSize, 32*o.YSize
+	img := image.NewPaletted(image.Rect(0, 0, width, height), o.colorPalette())
+
+	//Each pixel's edges are scaled separately, so pixels that don't divide the
+	//image evenly still cover it
+	screenWidth, screenHeight := cpu.ScreenWidth(), cpu.ScreenHeight()
+	for y := 0; y < screenHeight; y++ {
+		for x := 0; x < screenWidth; x++ {
+			v := cpu.VMem[y][x] & core.AllPlanes
+			if v == 0 {
+				continue
+			}
+			for py := y * height / screenHeight; py < (y+1)*height/screenHeight; py++ {
+				row := img.Pix[py*img.Stride:]
+				for px := x * width / screenWidth; px < (x+1)*width/screenWidth; px++ {
+					row[px] = v
+				}
+			}
+		}
+	}
+	return img
+}
+
+//SavePNG saves the display to a PNG file
+func SavePNG(path string, cpu *core.Chip8, o Options) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, Frame(cpu, o)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,149 @@
+package capture
+
+import (
+	"chip8emu/core"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFrame(t *testing.T) {
+	chip := core.NewChip8()
+	chip.VMem[1][2] = core.Plane1
+	o := Options{XSize: 2, YSize: 3, Palette: DefaultPalette}
+	o.Palette[0] = 0x123456
+
+	img := Frame(chip, o)
+	bg := color.RGBA{0x12, 0x34, 0x56, 0xff}
+	if img.Bounds().Dx() == 128 && img.Bounds().Dy() == 96 && img.At(4, 3) == img.Palette[1] &&
+		img.At(5, 5) == img.Palette[1] && img.At(6, 3) == bg && img.At(4, 6) == bg {
+		t.Log("Low resolution pixel scaled - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected image %v, pixel (4, 3) %v", img.Bounds(), img.At(4, 3))
+		t.Error(msg)
+	}
+
+	chip.HiRes = true
+	img = Frame(chip, o)
+	if img.Bounds().Dx() == 128 && img.At(2, 1) == img.Palette[1] && img.At(3, 1) == bg {
+		t.Log("High resolution pixel drawn at half size - Test Passed")
+	} else {
+		t.Error("Unexpected high resolution image")
+	}
+
+	//Odd sizes, with high resolution pixels alternately 1 and 2 wide, 2 and 1 high
+	o.XSize, o.YSize = 3, 3
+	for y := range chip.VMem[:64] {
+		for x := range chip.VMem[y][:128] {
+			chip.VMem[y][x] = core.Plane1
+		}
+	}
+	img = Frame(chip, o)
+	covered := true
+	for i, v := range img.Pix {
+		if v != 1 {
+			covered = false
+			t.Error("Pixel not drawn at ", i%img.Stride, ", ", i/img.Stride)
+			break
+		}
+	}
+	chip.VMem[63][127] = 0
+	img = Frame(chip, o)
+	if covered && img.At(189, 93) == img.Palette[1] && img.At(190, 94) == bg && img.At(191, 95) == bg {
+		t.Log("High resolution pixels cover the image at an odd size - Test Passed")
+	} else {
+		t.Error("Unexpected high resolution image at an odd size")
+	}
+
+	o.XSize, o.YSize = 1, 1
+	img = Frame(chip, o)
+	if img.At(63, 31) == bg && img.At(62, 31) == img.Palette[1] {
+		t.Log("Every other high resolution pixel drawn at size 1 - Test Passed")
+	} else {
+		t.Error("Unexpected high resolution image at size 1")
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "capture")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.gif")
+
+	chip := core.NewChip8()
+	display := core.NewHeadlessDisplay(chip)
+	r := NewRecorder(chip, path, Options{XSize: 1, YSize: 1, Palette: DefaultPalette})
+
+	//Three unchanged frames, two changes, then a change on a frame that would be
+	//shown for too short a time, so it appears a frame later
+	for i := 0; i < 3; i++ {
+		chip.DisplayHandler.Render()
+	}
+	for x := 0; x < 3; x++ {
+		chip.VMem[0][x*2] = 1
+		chip.DisplayHandler.Render()
+	}
+	chip.DisplayHandler.Render()
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, _ := os.Open(path)
+	anim, err := gif.DecodeAll(f)
+	f.Close()
+	if err == nil && len(anim.Image) == 4 && fmt.Sprint(anim.Delay) == "[5 2 3 2]" &&
+		anim.Image[3].Bounds() == image.Rect(4, 0, 5, 1) && chip.DisplayHandler == display {
+		t.Log("Changed areas recorded with 60Hz delays - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected recording, error %v", err)
+		if anim != nil {
+			msg += fmt.Sprintf(", %d frames delays %v", len(anim.Image), anim.Delay)
+		}
+		t.Error(msg)
+	}
+}
+
+func TestRecorderLastFrame(t *testing.T) {
+	dir, err := ioutil.TempDir("", "capture")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.gif")
+
+	chip := core.NewChip8()
+	core.NewHeadlessDisplay(chip)
+	r := NewRecorder(chip, path, Options{XSize: 1, YSize: 1, Palette: DefaultPalette})
+
+	//A change on the frame after a recorded change, too soon to be recorded, then
+	//nothing more before closing
+	chip.DisplayHandler.Render()
+	chip.VMem[0][0] = 1
+	chip.DisplayHandler.Render()
+	chip.VMem[0][2] = 1
+	chip.DisplayHandler.Render()
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, _ := os.Open(path)
+	anim, err := gif.DecodeAll(f)
+	f.Close()
+	if err == nil && len(anim.Image) == 3 && anim.Image[2].Bounds() == image.Rect(2, 0, 3, 1) &&
+		anim.Image[2].ColorIndexAt(2, 0) == 1 {
+		t.Log("Change on the final frame recorded - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected recording, error %v", err)
+		if anim != nil {
+			msg += fmt.Sprintf(", %d frames delays %v", len(anim.Image), anim.Delay)
+		}
+		t.Error(msg)
+	}
+}
@@ -0,0 +1,149 @@
+package capture
+
+import (
+	"chip8emu/core"
+	"image"
+	"image/gif"
+	"os"
+)
+
+//minDelay the shortest frame delay recorded, in hundredths of a second. Browsers
+//show frames with shorter delays for much longer, so frames that would be shown
+//for less are dropped.
+const minDelay = 2
+
+//Recorder records the frames rendered by the machine's display to an animated GIF.
+//Each frame only contains the area that changed since the previous one, and
+//frames where nothing changed extend the previous frame, keeping recordings small.
+type Recorder struct {
+	core.DisplayInterface
+	cpu  *core.Chip8
+	path string
+	opts Options
+
+	anim gif.GIF
+	//last the most recent frame recorded, in full
+	last *image.Paletted
+	//frames the number of frames rendered, starts the frame each recorded frame
+	//was rendered on
+	frames int
+	starts []int
+}
+
+//NewRecorder starts recording to a GIF at path, wrapping the machine's display so
+//that every frame it renders is recorded. The file is written by Close.
+func NewRecorder(cpu *core.Chip8, path string, o Options) *Recorder {
+	r := &Recorder{
+		DisplayInterface: cpu.DisplayHandler,
+		cpu:              cpu,
+		path:             path,
+		opts:             o,
+	}
+	r.anim.Config = image.Config{ColorModel: o.colorPalette(), Width: 64 * o.XSize, Height: 32 * o.YSize}
+	cpu.DisplayHandler = r
+	return r
+}
+
+//Render renders the frame on the wrapped display, then records it
+func (r *Recorder) Render() {
+	r.DisplayInterface.Render()
+	r.AddFrame()
+}
+
+//centis the time at which a frame is shown, in hundredths of a second
+func centis(frame int) int {
+	return (frame*100 + core.FrameRate/2) / core.FrameRate
+}
+
+//AddFrame records the current display as the next 60Hz frame
+func (r *Recorder) AddFrame() {
+	frame := r.frames
+	r.frames++
+	if r.last != nil && centis(frame)-centis(r.starts[len(r.starts)-1]) < minDelay {
+		return
+	}
+
+	r.record(Frame(r.cpu, r.opts), frame)
+}
+
+//record adds img, rendered on frame, to the recording if it differs from the last
+//frame recorded
+func (r *Recorder) record(img *image.Paletted, frame int) {
+	bounds := img.Bounds()
+	if r.last != nil {
+		bounds = changed(r.last, img)
+		if bounds.Empty() {
+			return
+		}
+	}
+	r.last = img
+
+	//Copy out the changed area, so only it is kept in memory
+	sub := image.NewPaletted(bounds, img.Palette)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		copy(sub.Pix[sub.PixOffset(bounds.Min.X, y):], img.Pix[img.PixOffset(bounds.Min.X, y):img.PixOffset(bounds.Max.X, y)])
+	}
+	r.anim.Image = append(r.anim.Image, sub)
+	r.anim.Disposal = append(r.anim.Disposal, gif.DisposalNone)
+	r.starts = append(r.starts, frame)
+}
+
+//changed the bounds of the pixels that differ between two frames
+func changed(a, b *image.Paletted) image.Rectangle {
+	bounds := image.Rectangle{}
+	for y := b.Rect.Min.Y; y < b.Rect.Max.Y; y++ {
+		rowA, rowB := a.Pix[y*a.Stride:(y+1)*a.Stride], b.Pix[y*b.Stride:(y+1)*b.Stride]
+		for x := range rowB {
+			if rowA[x] != rowB[x] {
+				bounds = bounds.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return bounds
+}
+
+//Frames the number of frames recorded, excluding those unchanged or dropped
+func (r *Recorder) Frames() int {
+	return len(r.anim.Image)
+}
+
+//Close stops recording, restoring the machine's display, and writes the GIF
+func (r *Recorder) Close() error {
+	if r.cpu.DisplayHandler == r {
+		r.cpu.DisplayHandler = r.DisplayInterface
+	}
+	if len(r.anim.Image) == 0 {
+		r.AddFrame()
+	} else {
+		//A change on a frame dropped for being too soon after the last one would be
+		//lost if the display didn't change again, so the final display is recorded
+		//as shown on the last frame
+		r.record(Frame(r.cpu, r.opts), r.frames-1)
+	}
+
+	r.anim.Delay = make([]int, len(r.starts))
+	for i, start := range r.starts {
+		end := r.frames
+		if i+1 < len(r.starts) {
+			end = r.starts[i+1]
+		}
+		if r.anim.Delay[i] = centis(end) - centis(start); r.anim.Delay[i] < minDelay {
+			r.anim.Delay[i] = minDelay
+		}
+	}
+
+	f, err := os.Create(r.path)
+	if err != nil {
+		return err
+	}
+	if err := gif.EncodeAll(f, &r.anim); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+//Path the file the recording is written to
+func (r *Recorder) Path() string {
+	return r.path
+}
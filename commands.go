@@ -0,0 +1,310 @@
+package main
+
+import (
+	"chip8emu/audio"
+	"chip8emu/capture"
+	"chip8emu/core"
+	"chip8emu/opts"
+	"chip8emu/trace"
+	"chip8emu/utils"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//disassemble writes source for a ROM to the output file, or stdout if there
+//isn't one
+func disassemble(opts *opts.DisasmOpts) error {
+	rom, err := ioutil.ReadFile(opts.Args.ROM)
+	if err != nil {
+		return err
+	}
+
+	write := func(w io.Writer) error {
+		return utils.WriteDisassembly(w, rom)
+	}
+	if opts.Linear {
+		write = func(w io.Writer) error {
+			addr := 0x200
+			for _, inst := range utils.Disassemble(rom) {
+				if _, err := fmt.Fprintf(w, "[0x%03X] (%04X) %v\n", addr, inst.Inst, inst.EmitStr); err != nil {
+					return err
+				}
+				addr += 2
+			}
+			return nil
+		}
+	}
+
+	if opts.Output == "" {
+		return write(os.Stdout)
+	}
+	return writeFile(opts.Output, write)
+}
+
+//assemble assembles a source file, writing the ROM and optionally a listing and
+//symbol table
+func assemble(opts *opts.AsmOpts) error {
+	src := opts.Args.Source
+	prog, err := utils.AssembleFile(src)
+	if err != nil {
+		return err
+	}
+	dst := opts.Output
+	if dst == "" {
+		dst = strings.TrimSuffix(src, filepath.Ext(src)) + ".ch8"
+	}
+	if err := ioutil.WriteFile(dst, prog.ROM, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Assembled %d bytes to %s\n", len(prog.ROM), dst)
+
+	if opts.Listing != "" {
+		if err := writeFile(opts.Listing, prog.WriteListing); err != nil {
+			return err
+		}
+	}
+	if opts.Symbols != "" {
+		if err := writeFile(opts.Symbols, prog.WriteSymbols); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//info prints a summary of a ROM
+func info(opts *opts.InfoOpts) error {
+	rom, err := ioutil.ReadFile(opts.Args.ROM)
+	if err != nil {
+		return err
+	}
+	info := utils.InspectROM(rom)
+	fmt.Printf("File:      %s\n", opts.Args.ROM)
+	fmt.Printf("Size:      %d bytes, %d reachable as code\n", info.Size, info.CodeSize)
+	fmt.Printf("Extension: %s\n", info.Extension)
+	fmt.Printf("SHA-1:     %s\n", info.SHA1)
+	return nil
+}
+
+//loadHeadless creates a machine without a display and loads a ROM into it
+func loadHeadless(m *opts.MachineOpts, path string) (*core.Chip8, error) {
+	c, err := newMachine(m)
+	if err != nil {
+		return nil, err
+	}
+	core.NewHeadlessDisplay(c)
+	if _, err := loadROM(c, path); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+//loadROM reads a ROM into the machine's memory, returning its contents
+func loadROM(c *core.Chip8, path string) ([]byte, error) {
+	rom, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.LoadProgram(rom); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return rom, nil
+}
+
+//traceROM runs a ROM headless for a number of instructions, tracing them to stdout
+//unless --trace gives a file
+func traceROM(opts *opts.TraceOpts) error {
+	if opts.Trace == "" {
+		opts.Trace = "-"
+	}
+	c, err := loadHeadless(&opts.MachineOpts, opts.Args.ROM)
+	if err != nil {
+		return err
+	}
+	return c.RunCycles(opts.Cycles)
+}
+
+//test runs a ROM headless for a number of frames then prints the screen, or
+//compares it with the expected screen, optionally capturing the display
+func test(opts *opts.TestOpts) error {
+	c, err := loadHeadless(&opts.MachineOpts, opts.Args.ROM)
+	if err != nil {
+		return err
+	}
+	if err := runRecorded(c, opts); err != nil {
+		return err
+	}
+	if opts.Screenshot != "" {
+		if err := capture.SavePNG(opts.Screenshot, c, capture.DefaultOptions()); err != nil {
+			return err
+		}
+	}
+	screen := c.ScreenText()
+	if opts.Expect == "" {
+		fmt.Print(screen)
+		return nil
+	}
+
+	expected, err := ioutil.ReadFile(opts.Expect)
+	if err != nil {
+		return err
+	}
+	if string(expected) != screen {
+		fmt.Print(screen)
+		return fmt.Errorf("%s: screen differs from %s", opts.Args.ROM, opts.Expect)
+	}
+	fmt.Printf("%s: screen matches %s\n", opts.Args.ROM, opts.Expect)
+	return nil
+}
+
+//runRecorded runs the frames for the test command, recording the display and beep
+//as requested
+func runRecorded(c *core.Chip8, opts *opts.TestOpts) error {
+	if opts.Record == "" && opts.WAV == "" {
+		return c.RunFrames(opts.Frames)
+	}
+
+	var recorder *capture.Recorder
+	if opts.Record != "" {
+		recorder = capture.NewRecorder(c, opts.Record, capture.DefaultOptions())
+	}
+	var wav *audio.WAVRecorder
+	if opts.WAV != "" {
+		var err error
+		if wav, err = audio.NewWAVRecorder(c, opts.WAV, audio.DefaultTone()); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	for i := 0; i < opts.Frames && err == nil; i++ {
+		err = c.RunFrames(1)
+		if recorder != nil {
+			recorder.AddFrame()
+		}
+		if wav != nil {
+			wav.AddFrame()
+		}
+	}
+	if recorder != nil {
+		if closeErr := recorder.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if wav != nil {
+		if closeErr := wav.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+//compare runs a ROM in lock-step with a reference trace, reporting the first
+//divergence
+func compare(opts *opts.CompareOpts) error {
+	c, err := loadHeadless(&opts.MachineOpts, opts.Args.ROM)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(opts.Args.Reference)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	cmp := &trace.Comparer{Context: opts.Context, Timers: opts.Timers}
+	d, err := cmp.Compare(c, trace.NewReader(f))
+	if err != nil {
+		return fmt.Errorf("%s: after %d instructions: %v", opts.Args.Reference, cmp.Steps, err)
+	}
+	if d != nil {
+		d.Write(os.Stdout)
+		return fmt.Errorf("%s: diverged after %d matching instructions", opts.Args.Reference, cmp.Steps)
+	}
+	fmt.Printf("%s: all %d instructions matched\n", opts.Args.Reference, cmp.Steps)
+	return nil
+}
+
+//replay plays back a movie headless as fast as possible, checking it ends on the
+//display it was recorded with
+func replay(opts *opts.ReplayOpts) error {
+	c := core.NewChip8()
+	core.NewHeadlessDisplay(c)
+	rom, err := loadROM(c, opts.Args.ROM)
+	if err != nil {
+		return err
+	}
+	movie, err := readMovie(opts.Args.Movie, rom)
+	if err != nil {
+		return err
+	}
+
+	var wav *audio.WAVRecorder
+	if opts.WAV != "" {
+		if wav, err = audio.NewWAVRecorder(c, opts.WAV, audio.DefaultTone()); err != nil {
+			return err
+		}
+	}
+
+	player := c.PlayMovie(movie)
+	for !player.Done() && err == nil {
+		if err = c.RunFrames(1); err == nil {
+			c.InputHandler.HandleInput()
+		}
+		if wav != nil {
+			wav.AddFrame()
+		}
+	}
+	if wav != nil {
+		if closeErr := wav.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%s: frame %d: %v", opts.Args.Movie, player.Frame(), err)
+	}
+	if opts.Screenshot != "" {
+		if err := capture.SavePNG(opts.Screenshot, c, capture.DefaultOptions()); err != nil {
+			return err
+		}
+	}
+	if err := player.Err(); err != nil {
+		return fmt.Errorf("%s: %v", opts.Args.Movie, err)
+	}
+	fmt.Printf("%s: all %d frames replayed, display matches the recording\n", opts.Args.Movie, len(movie.Frames))
+	return nil
+}
+
+//readMovie reads a movie file, checking it was recorded with the given ROM
+func readMovie(path string, rom []byte) (*utils.Movie, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	movie := &utils.Movie{}
+	if err := movie.Decode(f); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if movie.ROM != sha1.Sum(rom) {
+		return nil, fmt.Errorf("%s: recorded with a different ROM, SHA-1 %x", path, movie.ROM)
+	}
+	return movie, nil
+}
+
+//writeFile creates the file at path and writes it using write
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -1,25 +1,15 @@
 package core
 
-// typedef unsigned char Uint8;
-// void MyCallback(void *userdata, Uint8 *stream, int len);
-// void Chip8AudioCallback(void *userdata, Uint8 *stream, int len);
-import "C"
 import (
 	"chip8emu/utils"
 	"encoding/base64"
 	"fmt"
 	"io/ioutil"
 	"log"
-	"math"
-	"reflect"
+	"math/rand"
 	"time"
-	"unsafe"
-
-	"gopkg.in/veandco/go-sdl2.v0/sdl"
 )
 
-var AudioPlay bool
-
 var chip8 *Chip8
 
 const (
@@ -27,6 +17,15 @@ const (
 	displayHeight = 32
 )
 
+//Frame timing, the timers and display run at 60Hz
+const (
+	FrameRate     = 60
+	FrameDuration = time.Second / FrameRate
+)
+
+//DefaultCyclesPerFrame roughly matches the 500Hz clock at a 60Hz frame rate
+const DefaultCyclesPerFrame = 8
+
 //Chip-8 keypad keys.
 const (
 	ChipKey0 = 0
@@ -87,8 +86,6 @@ const (
 	CharBank15 = 0x4B
 )
 
-//sdl audio generate waveform
-
 //Stores memory addresses associated with each character bank.
 var charBank = [16]uint16{
 	CharBank0, CharBank1, CharBank2, CharBank3, CharBank4, CharBank5, CharBank6, CharBank7,
@@ -103,11 +100,12 @@ var chars = []uint8{
 }
 
 type handlerTable struct {
-	InstructionTable *map[int]func(*Chip8, uint16)
+	InstructionTable *map[int]func(*Chip8, uint16) error
 }
 
 //GetHandler used to retrieve the relevant instruction handler for the given opcode
-func (t *handlerTable) GetHandler(opcode uint16) func(*Chip8, uint16) {
+//Returns nil if the opcode is not recognised.
+func (t *handlerTable) GetHandler(opcode uint16) func(*Chip8, uint16) error {
 	var mask uint16
 	switch opcode & 0xF000 {
 	case 0x8000:
@@ -115,6 +113,9 @@ func (t *handlerTable) GetHandler(opcode uint16) func(*Chip8, uint16) {
 		break
 	case 0x0000:
 		mask = 0xF0FF
+		if opcode&0xFFF0 == ScrollDown || opcode&0xFFF0 == ScrollUp {
+			mask = 0xFFF0
+		}
 		break
 	case 0xE000:
 		mask = 0xF0FF
@@ -129,10 +130,17 @@ func (t *handlerTable) GetHandler(opcode uint16) func(*Chip8, uint16) {
 	return (*t.InstructionTable)[int(opcode&mask)]
 }
 
-func newHandlerTable() *map[int]func(*Chip8, uint16) {
-	var InstructionTable = map[int]func(*Chip8, uint16){
+func newHandlerTable() *map[int]func(*Chip8, uint16) error {
+	var InstructionTable = map[int]func(*Chip8, uint16) error{
 		Clear:                Handle0x0,
 		Return:               Handle0x0,
+		ScrollDown:           Handle0x0,
+		ScrollUp:             Handle0x0,
+		ScrollRight:          Handle0x0,
+		ScrollLeft:           Handle0x0,
+		Exit:                 Handle0x0,
+		LowRes:               Handle0x0,
+		HighRes:              Handle0x0,
 		Jump:                 Handle0x1,
 		JumpSub:              Handle0x2,
 		SkipVxEqKk:           Handle0x3,
@@ -156,41 +164,92 @@ func newHandlerTable() *map[int]func(*Chip8, uint16) {
 		DrawSprite:           Handle0xD,
 		SkipVxEqKey:          Handle0xE,
 		SkipVxNeqKey:         Handle0xE,
-		LoadVxFromK:          Handle0xE,
+		LoadVxFromK:          Handle0xF,
 		LoadVxFromDelayTimer: Handle0xF,
 		LoadDelayTimerFromVx: Handle0xF,
 		LoadSoundTimerFromVx: Handle0xF,
 		LoadIAddVx:           Handle0xF,
 		LoadSpriteCharacter:  Handle0xF,
+		LoadBigSpriteChar:    Handle0xF,
 		LoadIWithBcdOfVx:     Handle0xF,
 		StoreV0ToVx:          Handle0xF,
 		LoadIToV0ToVx:        Handle0xF,
+		LoadILong:            Handle0xF,
+		SelectPlane:          Handle0xF,
+		LoadAudioPattern:     Handle0xF,
+		LoadPitchFromVx:      Handle0xF,
+		StoreV0ToVxInRPL:     Handle0xF,
+		LoadRPLToV0ToVx:      Handle0xF,
 	}
 	return &InstructionTable
 }
 
-type inputHandler struct {
-	InputInterface
-}
-
-//DisplayInterface the expected methods that a renderer must implement
+//DisplayInterface the expected methods that a renderer must implement. The
+//machine runs until IsAlive reports false, e.g. once the window is closed.
 type DisplayInterface interface {
 	Render()
 	IsAlive() bool
-	HandleInput()
-	WaitForInput() uint8
 }
 
-//InputInterface the expected method that an input handler must implement
+//InputInterface the expected method that an input source must implement.
+//HandleInput is called once per frame to process pending events, updating the
+//keypad with SetKey and ClrKey. It must not block, FX0A waits for a key by
+//running cycles until one is pressed and released, see KeyWait.
 type InputInterface interface {
-	Read() uint16
+	HandleInput()
+}
+
+//AudioInterface the expected methods that an audio output must implement. Play
+//is called when the sound timer is set and on every frame it remains active, Stop
+//once it reaches zero.
+type AudioInterface interface {
+	Play()
+	Stop()
+}
+
+//ClockInterface the expected method that a clock source must implement, used to
+//pace the FDE cycle
+type ClockInterface interface {
+	Sleep(d time.Duration)
+}
+
+//DebuggerInterface the expected method that a debugger must implement. Service is
+//called on the machine's goroutine at the start of every frame, so the debugger
+//can safely inspect and modify the machine state.
+type DebuggerInterface interface {
+	Service()
+}
+
+//TracerInterface the expected method that an execution tracer must implement.
+//Trace is called with the address and opcode of each instruction before it is
+//executed, so the machine state is that seen by the instruction.
+type TracerInterface interface {
+	Trace(c *Chip8, pc, opcode uint16)
 }
 
 //Chip8 the core structure for managing machine state
 type Chip8 struct {
-	Memory [4096]uint8
+	//Memory, the XO-CHIP 64KiB address space. Original Chip-8 programs only use the
+	//first 4KiB.
+	Memory [0x10000]uint8
 	V      [16]uint8
-	VMem   [32][64]uint8
+	//Video memory, sized for the SUPER-CHIP high resolution mode, in low resolution
+	//only the top left 64x32 pixels are used.
+	VMem [hiresHeight][hiresWidth]uint8
+	//HiRes SUPER-CHIP 128x64 display mode
+	HiRes bool
+	//Halted set once the SUPER-CHIP exit instruction has been executed
+	Halted bool
+	//RPL user flags, saved and restored by FX75/FX85
+	RPL [16]uint8
+	//Planes XO-CHIP bitplanes selected for drawing
+	Planes uint8
+
+	//XO-CHIP audio pattern buffer, played at a rate set by Pitch while the sound
+	//timer is active. PatternLoaded is set once a program has loaded a pattern.
+	AudioPattern  [16]uint8
+	Pitch         uint8
+	PatternLoaded bool
 
 	//Address register
 	I uint16
@@ -202,22 +261,49 @@ type Chip8 struct {
 	//Stack Pointer
 	Sp uint8
 
+	//Timers, decremented at 60Hz, i.e. once every CyclesPerFrame instructions
 	DelayTimer uint8
 	SoundTimer uint8
 
-	DelayTicker *time.Ticker
-	SoundTicker *time.Ticker
+	//Number of instructions executed
+	Cycles uint64
 
 	Keys [16]uint8
+	//KeyWait the state of an FX0A waiting for a key
+	KeyWait KeyWait
 
 	DTDisabled bool
 	STDisabled bool
 
 	DisplayHandler   DisplayInterface
-	KBHandler        *inputHandler
+	InputHandler     InputInterface
+	AudioHandler     AudioInterface
+	Clock            ClockInterface
+	Debugger         DebuggerInterface
+	Tracer           TracerInterface
 	InstHandlerTable *handlerTable
-	GfxClipping      bool
+	Quirks           Quirks
 	MM               *utils.MachineMonitor
+
+	//Number of instructions making up a single 60Hz frame, the timers tick once
+	//per frame.
+	CyclesPerFrame int
+	//Turbo runs the machine as fast as possible, without pacing frames
+	Turbo bool
+
+	//Seed the seed of the random number generator used by CXNN, set by SeedRandom
+	//so that a run can be reproduced
+	Seed int64
+	rng  *rand.Rand
+
+	//beeping set while the audio handler is playing the beep
+	beeping bool
+	//rom the program loaded, restored by Reset
+	rom []byte
+
+	//The instruction being executed, reported by watchpoint hits
+	instPc     uint16
+	instOpcode uint16
 }
 
 //NewChip8 constructor to instantiate a machine
@@ -228,16 +314,8 @@ func NewChip8() (c *Chip8) {
 	return chip
 }
 
-//NewChip8WithHandler constructor to instantiate a machine with a specified input handler
-func NewChip8WithHandler(KBHandler *inputHandler) (c *Chip8) {
-	chip := NewChip8()
-	chip.KBHandler = KBHandler
-	return chip
-}
-
 func (c *Chip8) init() {
-	c.Sp = 15
-	c.Pc = 0x200
+	c.CyclesPerFrame = DefaultCyclesPerFrame
 	var address uint16
 	var i uint16
 	for i = 0; i < 16; i++ {
@@ -245,16 +323,62 @@ func (c *Chip8) init() {
 		address += 5
 	}
 
-	for i, e := range chars {
-		c.Memory[i] = e
-	}
-
 	//c.VMem = c.Memory[3840:]
 	c.InstHandlerTable = &handlerTable{}
 	c.InstHandlerTable.InstructionTable = newHandlerTable()
-	//c.GfxClipping = DrawClippingDisabled
 	c.MM = utils.NewMachineMonitor()
-	initBeep()
+	c.InputHandler = NullInput{}
+	c.AudioHandler = NullAudio{}
+	c.Clock = realClock{}
+	c.SeedRandom(time.Now().UnixNano())
+	c.powerOn()
+}
+
+//powerOn sets the machine state as when switched on, with memory holding only the
+//fonts and the loaded ROM
+func (c *Chip8) powerOn() {
+	c.Memory = [len(c.Memory)]uint8{}
+	c.V = [16]uint8{}
+	c.VMem = [hiresHeight][hiresWidth]uint8{}
+	c.HiRes = false
+	c.Halted = false
+	c.RPL = [16]uint8{}
+	c.Planes = Plane1
+	c.AudioPattern = [16]uint8{}
+	c.Pitch = DefaultPitch
+	c.PatternLoaded = false
+	c.I = 0
+	c.Pc = 0x200
+	c.S = [16]uint16{}
+	c.Sp = 15
+	c.DelayTimer = 0
+	c.SoundTimer = 0
+	c.Cycles = 0
+	c.Keys = [16]uint8{}
+	c.KeyWait = KeyWait{}
+
+	for i, e := range chars {
+		c.Memory[i] = e
+	}
+
+	for i, e := range bigChars {
+		c.Memory[BigCharBank+i] = e
+	}
+	copy(c.Memory[0x200:], c.rom)
+}
+
+//Reset restarts the machine as if switched off and on again, reloading the ROM.
+//The handlers and settings, e.g. the quirks, speed and random seed, are kept.
+func (c *Chip8) Reset() {
+	c.powerOn()
+	c.updateSound()
+	c.SeedRandom(c.Seed)
+}
+
+//SeedRandom restarts the random number generator from the given seed
+func (c *Chip8) SeedRandom(seed int64) {
+	c.Seed = seed
+	c.rng = rand.New(rand.NewSource(seed))
 }
 
 //LoadROM load a ROM provided as sa base64 encoded string.
@@ -265,95 +389,169 @@ func (c *Chip8) LoadROM(rom string) {
 		return
 	}
 
-	for i := range data {
-		c.Memory[0x200+i] = data[i]
+	if err := c.LoadProgram(data); err != nil {
+		fmt.Println("error:", err)
 	}
 }
 
-//startDelayTimer start delay time with number.
-//Thi number will tick down at a rate of 60Hz
-func (c *Chip8) startDelayTimer(num uint8) {
-
-	c.DelayTimer = num
-
-	if c.DTDisabled {
-		return
+//LoadProgram loads a ROM into memory at 0x200, keeping a copy for Reset
+func (c *Chip8) LoadProgram(rom []byte) error {
+	if len(rom) > len(c.Memory)-0x200 {
+		return fmt.Errorf("ROM too large, %d bytes", len(rom))
 	}
+	c.rom = append([]byte(nil), rom...)
+	copy(c.Memory[0x200:], rom)
+	return nil
+}
 
-	if c.DelayTicker != nil {
-		c.DelayTicker.Stop()
+//tickTimers decrements the delay and sound timers, called once per frame
+func (c *Chip8) tickTimers() {
+	if c.DelayTimer > 0 && !c.DTDisabled {
+		c.DelayTimer--
 	}
 
-	c.DelayTicker = time.NewTicker(time.Second / 60)
-	go func() {
-		for range c.DelayTicker.C {
-			c.DelayTimer--
+	if c.SoundTimer > 0 && !c.STDisabled {
+		c.SoundTimer--
+	}
+	c.updateSound()
+}
+
+//updateSound plays the beep for exactly as long as the sound timer is active.
+//Play is called every frame it is, so the audio can follow changes to the XO-CHIP
+//audio pattern.
+func (c *Chip8) updateSound() {
+	if c.SoundTimer > 0 {
+		c.AudioHandler.Play()
+		c.beeping = true
+	} else if c.beeping {
+		c.AudioHandler.Stop()
+		c.beeping = false
+	}
+}
 
-			if c.DelayTimer == 0 {
-				break
-			}
+//Start start the machine running, until either the display is closed or an
+//instruction fails, in which case the error is returned.
+//Each 60Hz frame runs a batch of CyclesPerFrame instructions, handles input and
+//renders, then sleeps until the frame's deadline unless running in Turbo.
+func (c *Chip8) Start() error {
+	deadline := time.Now()
+	for c.DisplayHandler.IsAlive() && !c.Halted {
+		if c.Debugger != nil {
+			c.Debugger.Service()
+		}
+		if err := c.runFrame(); err != nil {
+			return err
 		}
-		c.DelayTicker.Stop()
-		c.DelayTicker = nil
-	}()
 
+		c.InputHandler.HandleInput()
+		if !c.DisplayHandler.IsAlive() {
+			break
+		}
+		c.DisplayHandler.Render()
+
+		now := time.Now()
+		deadline = deadline.Add(FrameDuration)
+		if c.Turbo || deadline.Before(now.Add(-FrameDuration)) {
+			//Running unthrottled or too far behind to catch up, don't try to make
+			//up for lost frames.
+			deadline = now
+			continue
+		}
+		if wait := deadline.Sub(now); wait > 0 {
+			c.Clock.Sleep(wait)
+		}
+	}
+	return nil
 }
 
-//startSoundTimer start sound timer with specified number.
-//This number will tick down at 60Hz
-func (c *Chip8) startSoundTimer(num uint8) {
-
-	c.SoundTimer = num
-
-	if c.STDisabled {
-		return
+//runFrame runs a frame's worth of instructions, subject to the machine monitor
+func (c *Chip8) runFrame() error {
+	for i := 0; i < c.CyclesPerFrame && !c.Halted; i++ {
+		if !c.doFDECycle() {
+			continue
+		}
+		if err := c.Step(); err != nil {
+			return err
+		}
+		c.MM.Reset()
 	}
+	return nil
+}
 
-	if c.SoundTicker != nil {
-		c.SoundTicker.Stop()
+//SetCyclesPerFrame sets the emulated clock speed as a number of instructions per
+//60Hz frame, at least one.
+func (c *Chip8) SetCyclesPerFrame(n int) {
+	if n < 1 {
+		n = 1
 	}
+	c.CyclesPerFrame = n
+}
 
-	c.SoundTicker = time.NewTicker(time.Second / 60)
-	go func() {
+//SetClockHz sets the emulated clock speed in instructions per second
+func (c *Chip8) SetClockHz(hz int) {
+	c.SetCyclesPerFrame((hz + FrameRate/2) / FrameRate)
+}
 
-		for range c.SoundTicker.C {
+//ClockHz gets the emulated clock speed in instructions per second
+func (c *Chip8) ClockHz() int {
+	return c.CyclesPerFrame * FrameRate
+}
 
-			if c.SoundTimer == 0 {
-				continue
-			}
+//Step fetches and executes a single instruction, bypassing the machine monitor.
+//A failing instruction is reported as a *MachineError.
+func (c *Chip8) Step() error {
+	if c.Halted {
+		return nil
+	}
 
-			if c.SoundTimer > 0 {
-				playBeep()
-				c.SoundTimer--
-			}
+	pc := c.Pc
+	if err := c.checkMem(pc, 2); err != nil {
+		return &MachineError{Err: err, Pc: pc}
+	}
 
+	if c.KeyWait.Active {
+		c.waitForKey()
+	} else {
+		ins := c.fetch()
+		c.instPc, c.instOpcode = pc, ins
+		if c.Tracer != nil {
+			c.Tracer.Trace(c, pc, ins)
 		}
+		if err := c.execute(ins); err != nil {
+			return &MachineError{Err: err, Pc: pc, Opcode: ins}
+		}
+	}
 
-	}()
-
+	c.Cycles++
+	if c.CyclesPerFrame > 0 && c.Cycles%uint64(c.CyclesPerFrame) == 0 {
+		c.tickTimers()
+	}
+	return nil
 }
 
-//Start start the machine running
-func (c *Chip8) Start() {
-	alive := c.DisplayHandler.IsAlive()
-	for alive {
-		if c.doFDECycle() {
-			ins := c.fetch()
-			//FIXME: Allow error code to be returned from execute, so we can stop the processor
-			//FDE cycle if an invalid instruction is encountered.
-			c.execute(ins)
-			c.MM.Reset()
+//RunCycles executes n instructions back to back without pacing or input
+//handling, intended for headless use. Stops at the first failing instruction, or
+//once the machine has halted.
+func (c *Chip8) RunCycles(n int) error {
+	for i := 0; i < n && !c.Halted; i++ {
+		if err := c.Step(); err != nil {
+			return err
 		}
-		c.DisplayHandler.HandleInput()
-		alive = c.DisplayHandler.IsAlive()
-		sdl.Delay(2)
 	}
+	return nil
+}
+
+//RunFrames executes n frames worth of instructions, see CyclesPerFrame
+func (c *Chip8) RunFrames(n int) error {
+	return c.RunCycles(n * c.CyclesPerFrame)
 }
 
 func (c *Chip8) doFDECycle() bool {
 
 	if !c.MM.IsActive() {
-		if c.MM.IsBP(c.GetPc()) {
+		//The PC is already past an FX0A waiting for a key, so a breakpoint on
+		//the next instruction isn't reached until the wait is over
+		if c.MM.IsBP(c.GetPc()) && !c.MM.IsResuming() && !c.KeyWait.Active {
 			c.MM.Activate()
 			return false
 		}
@@ -394,34 +592,49 @@ func (c *Chip8) fetch() (opcode uint16) {
 	return op
 }
 
-func (c *Chip8) execute(inst uint16) {
+func (c *Chip8) execute(inst uint16) error {
 	h := c.InstHandlerTable.GetHandler(inst)
-	h(c, inst)
+	if h == nil {
+		return ErrInvalidOpcode
+	}
+	return h(c, inst)
+}
+
+//checkMem checks that n bytes starting at addr are within memory
+func (c *Chip8) checkMem(addr uint16, n int) error {
+	if int(addr)+n > len(c.Memory) {
+		return ErrMemoryOutOfBounds
+	}
+	return nil
 }
 
 //SetMem sets the specified memory location to the passed value
 func (c *Chip8) SetMem(mem, val uint16) {
-	c.Memory[mem] = uint8(val >> 8)
-	c.Memory[mem+1] = uint8(val & 0xFF)
+	c.writeMem(mem, uint8(val>>8))
+	c.writeMem(mem+1, uint8(val&0xFF))
 }
 
 //SetV set the specified register to the passed value
 func (c *Chip8) SetV(reg, val uint8) {
+	c.watch(utils.WatchV, utils.WatchWrite, uint16(reg), uint16(c.V[reg]), uint16(val))
 	c.V[reg] = val
 }
 
 //SetI sets the memory address regiter to the provided address
 func (c *Chip8) SetI(val uint16) {
+	c.watch(utils.WatchI, utils.WatchWrite, 0, c.I, val)
 	c.I = val
 }
 
 //GetI get the current address pointed to by the address register
 func (c *Chip8) GetI() uint16 {
+	c.watch(utils.WatchI, utils.WatchRead, 0, c.I, c.I)
 	return c.I
 }
 
 //GetV gets the value of the specified register
 func (c *Chip8) GetV(reg uint8) uint8 {
+	c.watch(utils.WatchV, utils.WatchRead, uint16(reg), uint16(c.V[reg]), uint16(c.V[reg]))
 	return c.V[reg]
 }
 
@@ -436,15 +649,22 @@ func (c *Chip8) GetPc() uint16 {
 }
 
 //Push pushes the specified value onto the stack
-func (c *Chip8) Push(val uint16) {
+func (c *Chip8) Push(val uint16) error {
+	if int(c.Sp) >= len(c.S) {
+		return ErrStackOverflow
+	}
 	c.S[c.Sp] = val
 	c.Sp--
+	return nil
 }
 
 //Pop pops the current value off the stack
-func (c *Chip8) Pop() uint16 {
+func (c *Chip8) Pop() (uint16, error) {
+	if int(c.Sp) == len(c.S)-1 {
+		return 0, ErrStackUnderflow
+	}
 	c.Sp++
-	return c.S[c.Sp]
+	return c.S[c.Sp], nil
 }
 
 //SetKey set the specified key as pressed
@@ -462,28 +682,63 @@ func (c *Chip8) GetKey(key uint8) uint8 {
 	return c.Keys[key]
 }
 
+//KeyWait the state of FX0A waiting for a key. As on the COSMAC VIP the wait is
+//over once a key is pressed and then released, so a program looping on FX0A
+//sees each press once. The machine keeps running cycles while waiting, without
+//executing instructions, so the timers count down and the front end, which
+//updates the keys between frames, stays responsive.
+type KeyWait struct {
+	//Active set by FX0A until the key is released
+	Active bool
+	//Reg the register loaded with the key
+	Reg uint8
+	//Pressed set once a key has been pressed, Key being that key
+	Pressed bool
+	Key     uint8
+}
+
+//waitForKey runs a cycle of an FX0A wait, loading the register once the key
+//pressed has been released. A key already held when FX0A started counts as
+//pressed, as the VIP reads the keypad rather than key presses.
+func (c *Chip8) waitForKey() {
+	w := &c.KeyWait
+	if !w.Pressed {
+		for key, pressed := range c.Keys {
+			if pressed != 0 {
+				w.Pressed, w.Key = true, uint8(key)
+				break
+			}
+		}
+		return
+	}
+	if c.Keys[w.Key] == 0 {
+		c.SetV(w.Reg, w.Key)
+		*w = KeyWait{}
+	}
+}
+
 //GetST gets the Sound Timer
 func (c *Chip8) GetST() uint8 {
+	c.watch(utils.WatchST, utils.WatchRead, 0, uint16(c.SoundTimer), uint16(c.SoundTimer))
 	return c.SoundTimer
 }
 
 //SetST initialises the sound timer with the specified value
 func (c *Chip8) SetST(val uint8) {
-	if c.SoundTicker == nil {
-		c.startSoundTimer(val)
-		return
-	}
-
+	c.watch(utils.WatchST, utils.WatchWrite, 0, uint16(c.SoundTimer), uint16(val))
 	c.SoundTimer = val
+	c.updateSound()
 }
 
 //SetDT initialises the delay timer with the specified value
 func (c *Chip8) SetDT(val uint8) {
-	c.startDelayTimer(val)
+	c.watch(utils.WatchDT, utils.WatchWrite, 0, uint16(c.DelayTimer), uint16(val))
+	c.DelayTimer = val
 }
 
 //GetDT retrieve the delay timer value
 func (c *Chip8) GetDT() uint8 {
+	c.watch(utils.WatchDT, utils.WatchRead, 0, uint16(c.DelayTimer), uint16(c.DelayTimer))
 	return c.DelayTimer
 }
 
@@ -537,155 +792,80 @@ func (c *Chip8) Draw(n uint8, x, y int32) {
 	c.WriteVMem(int(x), int(y), int(n))
 }
 
-//WriteVMem write sprite to video memory
+//WriteVMem write sprite to video memory. A height of 0 draws a SUPER-CHIP 16x16
+//sprite. The sprite origin always wraps onto the screen, whether the rest of the
+//sprite wraps or is clipped depends on Quirks.Wrap. With both XO-CHIP planes
+//selected the sprite data for the second plane follows that of the first.
 func (c *Chip8) WriteVMem(x, y, n int) {
 	c.SetV(VF, 0)
 	iReg := c.GetI()
+	x = wrapCoord(x, c.ScreenWidth())
+	y = wrapCoord(y, c.ScreenHeight())
+
+	spriteWidth := 8
+	if n == 0 {
+		spriteWidth, n = 16, 16
+	}
+
+	for _, plane := range c.selectedPlanes() {
+		iReg = c.drawPlane(plane, iReg, x, y, n, spriteWidth)
+	}
+}
+
+//drawPlane draws a sprite of the given dimensions from memory at iReg to a single
+//plane, returning the address following the sprite data.
+func (c *Chip8) drawPlane(plane uint8, iReg uint16, x, y, n, spriteWidth int) uint16 {
+	width, height := c.ScreenWidth(), c.ScreenHeight()
 
 	for i := 0; i < n; i++ {
 
-		if !(y < displayHeight && y >= 0) {
-			continue
+		data := uint16(c.readMem(iReg)) << 8
+		iReg++
+		if spriteWidth == 16 {
+			data |= uint16(c.readMem(iReg))
+			iReg++
 		}
 
-		data := c.Memory[iReg]
-		dx := x
+		dy := y + i
+		if dy >= height {
+			if !c.Quirks.Wrap {
+				continue
+			}
+			dy %= height
+		}
 
-		for j := 0; j < 8; j++ {
+		for j := 0; j < spriteWidth; j++ {
 
-			if !(dx < displayWidth && dx >= 0) {
-				break
+			dx := x + j
+			if dx >= width {
+				if !c.Quirks.Wrap {
+					break
+				}
+				dx %= width
 			}
 
-			on := (0x80 & data) > 1
+			on := (0x8000 & data) > 1
 			data <<= 1
 			if on {
-				if c.VMem[y][dx] > 0 {
+				if c.VMem[dy][dx]&plane > 0 {
 					c.SetV(VF, 1)
 				}
-				c.VMem[y][dx] ^= 0xFF
+				c.VMem[dy][dx] ^= plane
 			}
-			dx++
 		}
-		iReg++
-		y++
 	}
+	return iReg
 }
 
-//ClearScreenMem used to clear the video memory
-func (c *Chip8) ClearScreenMem() {
-	vmem := c.Memory[3840:]
-	for i := 0; i < 256; i++ {
-		vmem[i] = 0
-	}
+func wrapCoord(v, size int) int {
+	return ((v % size) + size) % size
+}
 
-	for y := 0; y < 32; y++ {
-		for x := 0; x < 64; x++ {
-			c.VMem[y][x] = 0
+//ClearScreenMem used to clear the selected planes of the video memory
+func (c *Chip8) ClearScreenMem() {
+	for y := range c.VMem {
+		for x := range c.VMem[y] {
+			c.VMem[y][x] &^= c.Planes
 		}
 	}
 }
-
-func initBeep() {
-	sdl.PauseAudio(true)
-	desired := &sdl.AudioSpec{}
-	desired.Freq = 44100
-	desired.Format = sdl.AUDIO_S8
-	desired.Channels = 1
-	desired.Samples = 2048
-	//desired.Size = 176400
-	desired.Callback = sdl.AudioCallback(C.MyCallback)
-	sdl.OpenAudio(desired, nil)
-
-}
-
-func playBeep() {
-	sdl.PauseAudio(false)
-}
-
-func stopBeep() {
-	sdl.PauseAudio(true)
-}
-
-// func callback() {
-// 	ass := C.int
-// 	D := C.Uint8
-// }
-
-const (
-	toneHz   = 300
-	sampleHz = 44100
-
-//	dPhase   = 2 * math.Pi * toneHz / sampleHz
-)
-
-//MyCallback foo
-//export MyCallback
-func MyCallback(userdata unsafe.Pointer, stream *C.Uint8, length C.int) {
-	//	toneHz := 440
-	//sampleHz := 48000
-	dPhase := 2 * math.Pi * toneHz / sampleHz
-	n := int(length)
-
-	fmt.Printf("BUFFER LEN: %d\n", n)
-
-	hdr := reflect.SliceHeader{Data: uintptr(unsafe.Pointer(stream)), Len: n, Cap: n}
-	buf := *(*[]C.Uint8)(unsafe.Pointer(&hdr))
-
-	var phase float64
-	for i := 0; i < n; i += 2 {
-		phase += dPhase
-		sample := C.Uint8((math.Sin(phase) + 0.999999) * 128)
-		buf[i] = sample
-		buf[i+1] = sample
-		// buf[i+2] = sample
-		// buf[i+3] = sample
-	}
-	sdl.PauseAudio(true)
-}
-
-//export Chip8AudioCallback
-func Chip8AudioCallback(userdata unsafe.Pointer, stream *C.Uint8, length C.int) {
-	//y(t) = A * sin(2*PI*frequency*time + phase)
-	sampleFrequency := 44100.0
-	amplitude := 100.0
-	//phase := 0.0
-	time := 1.0 / sampleFrequency
-	//bufferSize := int(frequency) * 4
-	//ticker := 1.0 / 60
-
-	//dPhase := 2 * math.Pi * toneHz / sampleHz
-
-	//chip := main.GetChip()
-	//n := int((chip8.GetST() * 16))
-	//n += int(n * n)
-	n := int(length)
-	//	maxWrite := int(ticker / time)
-
-	fmt.Printf("LEN: %d\n", n)
-
-	hdr := reflect.SliceHeader{Data: uintptr(unsafe.Pointer(stream)), Len: n, Cap: n}
-	buf := *(*[]C.Uint8)(unsafe.Pointer(&hdr))
-
-	//var phase float64
-	//st := chip8.GetST()
-	//var writeCnt = uint8(frequency/60.0) * st
-	var t float64
-	for i := 0; i < n; i += 2 {
-		//phase += dPhase
-		//sample := C.Uint8((math.Sin(phase) + 0.2) * 128)
-		y := math.Sin(2*math.Pi*440/sampleFrequency*time + 0.0)
-		sample := C.Uint8(amplitude * y)
-		t += time
-		//if writeCnt < maxWrite {
-		buf[i] = sample
-		buf[i+1] = sample
-		//}
-
-		//buf[i+2] = sample
-		//buf[i+3] = sample
-	}
-
-	//sdl.Delay(uint32(chip8.GetST() * 16))
-	sdl.PauseAudio(true)
-}
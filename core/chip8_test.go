@@ -1,6 +1,9 @@
 package core
 
 import (
+	"bytes"
+	"chip8emu/utils"
+	"errors"
 	"fmt"
 	"testing"
 )
@@ -514,17 +517,40 @@ func TestDecodeOpCodeType0xE_SKIP_ON_NO_KEYPRESS(t *testing.T) {
 
 }
 
-//func TestDecodeOpCodeType0xE_WAIT_FOR_KEYINPUT(t *testing.T) {
-//
-//	chip := NewChip8WithHandler(new(Handler))
-//	var op uint16 = LD_SET_VX_TO_K | (0x0200)
-//	v2 := chip.GetV(2)
-//	fmt.Printf("V2 (BEFORE) = %v\n", v2)
-//	HandleOpCodeType0xE_WAIT_FOR_KEYPRESS(chip, op)
-//	v2 = chip.GetV(2)
-//	fmt.Printf("V2 (AFTER) = %v\n", v2)
-//
-//}
+func TestDecodeOpCodeType0xF_WAIT_FOR_KEY(t *testing.T) {
+	chip := NewChip8()
+	NewHeadlessDisplay(chip)
+	chip.SetMem(0x200, LoadVxFromK|0x200)  //V2 = key
+	chip.SetMem(0x202, LoadVxFromKk|0x301) //V3 = 1
+	chip.SetDT(10)
+
+	chip.RunFrames(2)
+	if chip.KeyWait.Active && chip.Pc == 0x202 && chip.GetDT() == 8 {
+		t.Log("LD, Vx = K waits with the timers running - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected a wait at 0x202 with DT 8, actual %+v at 0x%03X with DT %d", chip.KeyWait, chip.Pc, chip.GetDT())
+		t.Error(msg)
+	}
+
+	chip.SetKey(ChipKeyB)
+	chip.RunFrames(1)
+	pressed := chip.KeyWait.Active && chip.GetV(V2) == 0
+	chip.ClrKey(ChipKeyB)
+	chip.RunCycles(1)
+	if pressed && !chip.KeyWait.Active && chip.GetV(V2) == ChipKeyB && chip.GetV(V3) == 0 {
+		t.Log("LD, Vx = K completes when the key is released - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected V2 = B once released, actual %X, waiting while pressed %v", chip.GetV(V2), pressed)
+		t.Error(msg)
+	}
+
+	chip.RunCycles(1)
+	if chip.GetV(V3) == 1 {
+		t.Log("Execution continues after the wait - Test Passed")
+	} else {
+		t.Error("Expected the instruction after LD, Vx = K to run")
+	}
+}
 
 func TestDecodeOpCodeType0xF_LD_VX_WITH_DT(t *testing.T) {
 	chip := NewChip8()
@@ -736,3 +762,459 @@ func TestExecuteInstruction(t *testing.T) {
 //	chip.DoTestStart()
 //
 //}
+
+func TestExecuteInvalidOpcode(t *testing.T) {
+	chip := NewChip8()
+	chip.SetMem(0x200, 0x5121)
+	err := chip.Step()
+
+	var merr *MachineError
+	if errors.As(err, &merr) && errors.Is(err, ErrInvalidOpcode) && merr.Pc == 0x200 && merr.Opcode == 0x5121 {
+		t.Log("Invalid opcode reported with PC and opcode - Test Passed")
+	} else {
+		t.Error("Expected ErrInvalidOpcode at 0x200, received: ", err)
+	}
+}
+
+func TestExecuteStackOverflow(t *testing.T) {
+	chip := NewChip8()
+	//CALL 0x200, recursing until the stack is exhausted
+	chip.SetMem(0x200, JumpSub|0x200)
+	err := chip.RunCycles(17)
+
+	if errors.Is(err, ErrStackOverflow) {
+		t.Log("Stack overflow reported - Test Passed")
+	} else {
+		t.Error("Expected ErrStackOverflow, received: ", err)
+	}
+
+	chip = NewChip8()
+	chip.SetMem(0x200, Return)
+	err = chip.Step()
+
+	if errors.Is(err, ErrStackUnderflow) {
+		t.Log("Stack underflow reported - Test Passed")
+	} else {
+		t.Error("Expected ErrStackUnderflow, received: ", err)
+	}
+}
+
+func TestExecuteMemoryOutOfBounds(t *testing.T) {
+	chip := NewChip8()
+	chip.SetI(uint16(len(chip.Memory) - 2))
+	var op uint16 = StoreV0ToVx | (0x0400)
+	err := Handle0xF(chip, op)
+
+	if errors.Is(err, ErrMemoryOutOfBounds) {
+		t.Log("Out of bounds store reported - Test Passed")
+	} else {
+		t.Error("Expected ErrMemoryOutOfBounds, received: ", err)
+	}
+}
+
+func TestQuirksShiftVy(t *testing.T) {
+	chip := NewChip8()
+	chip.Quirks, _ = QuirksProfile(QuirksVIP)
+	chip.SetV(V2, 0)
+	chip.SetV(V4, 9)
+	var op uint16 = LoadVxShiftR | (0x0024 << 4)
+	Handle0x8(chip, op)
+
+	if chip.GetV(V2) == 4 && chip.GetV(VF) == 1 {
+		t.Log("VIP SHR, Vx = Vy >> 1 - Test Passed")
+	} else {
+		msg := fmt.Sprintf("V2 = %v, VF = %v", chip.GetV(V2), chip.GetV(VF))
+		t.Error(msg)
+	}
+}
+
+func TestQuirksJumpVx(t *testing.T) {
+	chip := NewChip8()
+	chip.Quirks, _ = QuirksProfile(QuirksSChip)
+	chip.SetV(V2, 1)
+	var op uint16 = JumpPlusA0 | (0x0222)
+	Handle0xB(chip, op)
+
+	if chip.GetPc() == 0x0223 {
+		t.Log("SCHIP JP, XNN PLUS VX - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected PC = %v, actual PC = %v\n", 0x0223, chip.GetPc())
+		t.Error(msg)
+	}
+}
+
+func TestQuirksLoadStoreIncI(t *testing.T) {
+	chip := NewChip8()
+	chip.Quirks.LoadStoreIncI = true
+	chip.SetI(200)
+	var op uint16 = StoreV0ToVx | (0x0400)
+	Handle0xF(chip, op)
+
+	if chip.GetI() == 205 {
+		t.Log("STORE V0 TO VX increments I - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected I to be 205, was actually %v", chip.GetI())
+		t.Error(msg)
+	}
+}
+
+func TestQuirksWrap(t *testing.T) {
+	chip := NewChip8()
+	chip.Memory[0x300] = 0xFF
+	chip.SetI(0x300)
+	chip.Draw(1, 60, 0)
+
+	if chip.VMem[0][63] > 0 && chip.VMem[0][0] == 0 {
+		t.Log("Sprite clipped at right edge - Test Passed")
+	} else {
+		t.Error("Expected sprite to be clipped at the right edge")
+	}
+
+	chip.Quirks.Wrap = true
+	chip.ClearScreenMem()
+	chip.Draw(1, 60, 0)
+
+	if chip.VMem[0][63] > 0 && chip.VMem[0][3] > 0 && chip.VMem[0][4] == 0 {
+		t.Log("Sprite wrapped at right edge - Test Passed")
+	} else {
+		t.Error("Expected sprite to wrap at the right edge")
+	}
+}
+
+func TestQuirksSet(t *testing.T) {
+	var q Quirks
+	if err := q.Set("shift-vy=on"); err != nil || !q.ShiftVy {
+		t.Error("Failed to set shift-vy quirk: ", err)
+	}
+	if err := q.Set("shift-vy=off"); err != nil || q.ShiftVy {
+		t.Error("Failed to clear shift-vy quirk: ", err)
+	}
+	if err := q.Set("bogus"); err == nil {
+		t.Error("Expected error for unknown quirk")
+	}
+	if _, err := QuirksProfile("nonsense"); err == nil {
+		t.Error("Expected error for unknown profile")
+	}
+}
+
+func TestSChipHiResDraw(t *testing.T) {
+	chip := NewChip8()
+	Handle0x0(chip, HighRes)
+	chip.SetI(GetBigCharBank(8))
+	chip.SetV(V0, 120)
+	chip.SetV(V1, 60)
+	var op uint16 = DrawSprite | (0x0010)
+	Handle0xD(chip, op)
+
+	if chip.ScreenWidth() == 128 && chip.VMem[60][122] > 0 && chip.VMem[63][127] > 0 && chip.VMem[0][0] == 0 {
+		t.Log("DRW 16x16 sprite in high resolution - Test Passed")
+	} else {
+		t.Error("Expected 16x16 sprite clipped at bottom right of the 128x64 display")
+	}
+}
+
+func TestSChipScroll(t *testing.T) {
+	chip := NewChip8()
+	chip.VMem[0][0] = Plane1
+	Handle0x0(chip, ScrollDown|0x3)
+	Handle0x0(chip, ScrollRight)
+
+	if chip.VMem[3][4] > 0 && chip.VMem[0][0] == 0 {
+		t.Log("SCD 3, SCR - Test Passed")
+	} else {
+		t.Error("Expected pixel scrolled from (0,0) to (4,3)")
+	}
+
+	Handle0x0(chip, ScrollLeft)
+	if chip.VMem[3][0] > 0 && chip.VMem[3][4] == 0 {
+		t.Log("SCL - Test Passed")
+	} else {
+		t.Error("Expected pixel scrolled from (4,3) to (0,3)")
+	}
+}
+
+func TestSChipRPLAndExit(t *testing.T) {
+	chip := NewChip8()
+	chip.SetV(V0, 1)
+	chip.SetV(V1, 2)
+	Handle0xF(chip, StoreV0ToVxInRPL|0x0100)
+	chip.SetV(V0, 0)
+	chip.SetV(V1, 0)
+	Handle0xF(chip, LoadRPLToV0ToVx|0x0100)
+
+	if chip.GetV(V0) == 1 && chip.GetV(V1) == 2 {
+		t.Log("LD R, VX and LD VX, R - Test Passed")
+	} else {
+		t.Error("RPL flags not saved and restored")
+	}
+
+	chip.SetMem(0x200, Exit)
+	chip.SetMem(0x202, Jump|0x300)
+	chip.RunCycles(2)
+
+	if chip.Halted && chip.GetPc() == 0x202 {
+		t.Log("EXIT halts the machine - Test Passed")
+	} else {
+		t.Error("Expected machine to halt after EXIT")
+	}
+}
+
+func TestXOChipLongILoadAndSkip(t *testing.T) {
+	chip := NewChip8()
+	chip.SetMem(0x200, LoadILong)
+	chip.SetMem(0x202, 0x1234)
+	chip.RunCycles(1)
+
+	if chip.GetI() == 0x1234 && chip.GetPc() == 0x204 {
+		t.Log("I := LONG NNNN - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected I = 0x1234, PC = 0x204, actual I = %x, PC = %x", chip.GetI(), chip.GetPc())
+		t.Error(msg)
+	}
+
+	chip = NewChip8()
+	chip.SetMem(0x200, SkipVxEqKk|0x0000)
+	chip.SetMem(0x202, LoadILong)
+	chip.SetMem(0x204, 0x1234)
+	chip.RunCycles(1)
+
+	if chip.GetPc() == 0x206 {
+		t.Log("Skip over 4 byte instruction - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected PC = 0x206, actual PC = %x", chip.GetPc())
+		t.Error(msg)
+	}
+}
+
+func TestXOChipSaveLoadRange(t *testing.T) {
+	chip := NewChip8()
+	chip.SetI(0x300)
+	chip.SetV(V3, 3)
+	chip.SetV(V4, 4)
+	chip.SetV(V5, 5)
+	Handle0x5(chip, SaveVxToVy|0x0530)
+
+	if Mem(0x300, chip) == 5 && Mem(0x301, chip) == 4 && Mem(0x302, chip) == 3 && chip.GetI() == 0x300 {
+		t.Log("SAVE V5 - V3 - Test Passed")
+	} else {
+		t.Error("V5 to V3 not saved at I in reverse order")
+	}
+
+	Handle0x5(chip, LoadVxToVy|0x0AC0)
+	if chip.GetV(VA) == 5 && chip.GetV(VB) == 4 && chip.GetV(VC) == 3 {
+		t.Log("LOAD VA - VC - Test Passed")
+	} else {
+		t.Error("VA to VC not loaded from I")
+	}
+}
+
+func TestXOChipPlanes(t *testing.T) {
+	chip := NewChip8()
+	chip.Memory[0x300] = 0x80
+	chip.Memory[0x301] = 0x80
+	chip.SetI(0x300)
+	Handle0xF(chip, SelectPlane|0x0300)
+	chip.Draw(1, 0, 0)
+
+	if chip.VMem[0][0] == AllPlanes {
+		t.Log("Draw to both planes - Test Passed")
+	} else {
+		t.Error("Expected pixel set in both planes, was ", chip.VMem[0][0])
+	}
+
+	Handle0xF(chip, SelectPlane|0x0200)
+	Handle0x0(chip, Clear)
+	if chip.VMem[0][0] == Plane1 {
+		t.Log("Clear plane 2 only - Test Passed")
+	} else {
+		t.Error("Expected only plane 1 to remain, was ", chip.VMem[0][0])
+	}
+}
+
+func TestXOChipAudioPattern(t *testing.T) {
+	chip := NewChip8()
+	chip.Memory[0x300] = 0x80
+	chip.SetI(0x300)
+	Handle0xF(chip, LoadAudioPattern)
+	chip.SetV(V1, 112)
+	Handle0xF(chip, LoadPitchFromVx|0x0100)
+
+	if chip.PatternLoaded && chip.PatternBit(0) && !chip.PatternBit(1) && chip.PatternRate() == 8000 {
+		t.Log("AUDIO, PITCH := V1 - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected pattern state, rate %v", chip.PatternRate())
+		t.Error(msg)
+	}
+}
+
+func TestSaveLoadState(t *testing.T) {
+	chip := NewChip8()
+	chip.DisableDelayTimer()
+	chip.DisableSoundTimer()
+	chip.Quirks, _ = QuirksProfile(QuirksVIP)
+	chip.SetV(V3, 42)
+	chip.SetI(0x345)
+	chip.Push(0x222)
+	chip.Memory[0x400] = 0xAB
+	chip.VMem[10][20] = Plane1
+	chip.SetDT(30)
+	chip.KeyWait = KeyWait{Active: true, Reg: V4, Pressed: true, Key: ChipKey7}
+
+	var buf bytes.Buffer
+	if err := chip.SaveState(&buf); err != nil {
+		t.Fatal("SaveState failed: ", err)
+	}
+
+	restored := NewChip8()
+	restored.DisableDelayTimer()
+	restored.DisableSoundTimer()
+	if err := restored.LoadState(&buf); err != nil {
+		t.Fatal("LoadState failed: ", err)
+	}
+
+	if restored.GetV(V3) == 42 && restored.GetI() == 0x345 && restored.Sp == chip.Sp &&
+		restored.S[15] == 0x222 && restored.Memory[0x400] == 0xAB && restored.VMem[10][20] == Plane1 &&
+		restored.GetDT() == 30 && restored.Quirks == chip.Quirks && restored.KeyWait == chip.KeyWait {
+		t.Log("Save and restore machine state - Test Passed")
+	} else {
+		t.Error("Restored state differs from saved state")
+	}
+
+	if err := restored.LoadState(bytes.NewReader([]byte("not a state"))); err == nil {
+		t.Error("Expected error loading invalid state")
+	}
+}
+
+func TestTimersTickPerFrame(t *testing.T) {
+	chip := NewChip8()
+	chip.CyclesPerFrame = 10
+	//JP 0x200, spin
+	chip.SetMem(0x200, Jump|0x200)
+	chip.SetDT(5)
+	chip.SetST(2)
+
+	chip.RunCycles(9)
+	if chip.GetDT() != 5 {
+		t.Error("Delay timer ticked before the end of the frame: ", chip.GetDT())
+	}
+
+	chip.RunFrames(3)
+	if chip.GetDT() == 2 && chip.GetST() == 0 {
+		t.Log("Timers tick once per frame - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected DT = 2, ST = 0, actual DT = %v, ST = %v", chip.GetDT(), chip.GetST())
+		t.Error(msg)
+	}
+}
+
+//beepRecorder an audio handler noting whether the beep is playing
+type beepRecorder struct {
+	playing bool
+}
+
+func (b *beepRecorder) Play() { b.playing = true }
+func (b *beepRecorder) Stop() { b.playing = false }
+
+func TestSoundTimerCleared(t *testing.T) {
+	chip := NewChip8()
+	beep := &beepRecorder{}
+	chip.AudioHandler = beep
+	chip.CyclesPerFrame = 2
+	chip.SetMem(0x200, LoadVxFromKk|0x00A) //V0 = 10
+	chip.SetMem(0x202, LoadSoundTimerFromVx)
+	chip.SetMem(0x204, LoadVxFromKk|0x000) //V0 = 0
+	chip.SetMem(0x206, LoadSoundTimerFromVx|0x000)
+	chip.SetMem(0x208, Jump|0x208)
+
+	chip.RunFrames(1)
+	playing := beep.playing
+	chip.RunCycles(2)
+	if playing && !beep.playing && chip.GetST() == 0 {
+		t.Log("Beep stops when the sound timer is set to 0 - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected the beep to stop on LD ST, 0, actual playing before %v, after %v", playing, beep.playing)
+		t.Error(msg)
+	}
+}
+
+func TestWatchpoints(t *testing.T) {
+	chip := NewChip8()
+	//LD V0, 5; LD I, 0x300; LD [I], V0
+	chip.SetMem(0x200, LoadVxFromKk|0x005)
+	chip.SetMem(0x202, LoadIFromNnn|0x300)
+	chip.SetMem(0x204, StoreV0ToVx)
+	chip.SetMem(0x206, Jump|0x206)
+
+	chip.MM.AddWatch(utils.Watchpoint{Kind: utils.WatchMem, Start: 0x300, End: 0x30F, Access: utils.WatchWrite})
+	chip.MM.AddWatch(utils.Watchpoint{Kind: utils.WatchI, Access: utils.WatchRead})
+	chip.RunCycles(2)
+
+	if !chip.MM.IsActive() && len(chip.MM.TakeHits()) == 0 {
+		t.Log("No watchpoint hits before the store - Test Passed")
+	} else {
+		t.Error("Unexpected watchpoint hit before the store")
+	}
+
+	chip.RunCycles(1)
+	hits := chip.MM.TakeHits()
+	if chip.MM.IsActive() && len(hits) == 2 &&
+		hits[0].Watch.Kind == utils.WatchI && hits[0].Old == 0x300 &&
+		hits[1].Watch.Kind == utils.WatchMem && hits[1].Index == 0x300 && hits[1].Old == 0 && hits[1].New == 5 &&
+		hits[1].Pc == 0x204 && hits[1].Opcode == StoreV0ToVx {
+		t.Log("Read of I and write to memory trigger watchpoints - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected watchpoint hits %+v", hits)
+		t.Error(msg)
+	}
+}
+
+func TestWatchpointOneHitPerRead(t *testing.T) {
+	chip := NewChip8()
+	//LD I, 0x300; DRW V0, V1, 1; SAVE V0, V1; LOAD V0, V1
+	chip.SetMem(0x200, LoadIFromNnn|0x300)
+	chip.SetMem(0x202, DrawSprite|0x011)
+	chip.SetMem(0x204, SaveVxToVy|0x010)
+	chip.SetMem(0x206, LoadVxToVy|0x010)
+	chip.SetMem(0x208, Jump|0x208)
+	chip.MM.AddWatch(utils.Watchpoint{Kind: utils.WatchI, Access: utils.WatchRead})
+
+	var counts []int
+	for i := 0; i < 4; i++ {
+		chip.RunCycles(1)
+		counts = append(counts, len(chip.MM.TakeHits()))
+		chip.MM.Continue()
+	}
+	if fmt.Sprint(counts) == "[0 1 1 1]" {
+		t.Log("One watchpoint hit per instruction reading I - Test Passed")
+	} else {
+		t.Error("Expected one hit for each of DXYN, 5XY2 and 5XY3, actual ", counts)
+	}
+}
+
+func TestReset(t *testing.T) {
+	chip := NewChip8()
+	chip.SeedRandom(1)
+	//RND V0, $FF; LD V1, 7; LD I, $200; overwrite the start of the program with V0-V1
+	chip.LoadProgram([]byte{0xC0, 0xFF, 0x61, 0x07, 0xA2, 0x00, 0xF1, 0x55, 0x12, 0x08})
+	chip.SetCyclesPerFrame(5)
+	chip.RunCycles(5)
+	random := chip.V[V0]
+
+	chip.Reset()
+	if chip.Pc == 0x200 && chip.V[V1] == 0 && chip.I == 0 && chip.Cycles == 0 && chip.Memory[0x200] == 0xC0 &&
+		chip.Memory[0] == chars[0] && chip.CyclesPerFrame == 5 {
+		t.Log("Reset restores the power on state and the ROM - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected state after reset: PC %X, V1 %X, I %X, memory at 0x200 %X",
+			chip.Pc, chip.V[V1], chip.I, chip.Memory[0x200])
+		t.Error(msg)
+	}
+
+	chip.RunCycles(1)
+	if chip.V[V0] == random {
+		t.Log("Reset restarts the random numbers - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected V0 = %X, actual %X", random, chip.V[V0])
+		t.Error(msg)
+	}
+}
@@ -1,11 +1,5 @@
 package core
 
-import (
-	"fmt"
-	"math/rand"
-	"time"
-)
-
 //Instructions and other constants
 const (
 	opMask   = 0xF000
@@ -50,6 +44,24 @@ const (
 	LoadIWithBcdOfVx     = 0xF033
 	StoreV0ToVx          = 0xF055
 	LoadIToV0ToVx        = 0xF065
+	//SUPER-CHIP instructions
+	ScrollDown        = 0x00C0
+	ScrollRight       = 0x00FB
+	ScrollLeft        = 0x00FC
+	Exit              = 0x00FD
+	LowRes            = 0x00FE
+	HighRes           = 0x00FF
+	LoadBigSpriteChar = 0xF030
+	StoreV0ToVxInRPL  = 0xF075
+	LoadRPLToV0ToVx   = 0xF085
+	//XO-CHIP instructions
+	ScrollUp         = 0x00D0
+	SaveVxToVy       = 0x5002
+	LoadVxToVy       = 0x5003
+	LoadILong        = 0xF000
+	SelectPlane      = 0xF001
+	LoadAudioPattern = 0xF002
+	LoadPitchFromVx  = 0xF03A
 )
 
 func shift12(val uint16) uint8 {
@@ -99,103 +111,171 @@ func decimalToBcd(val uint16) uint16 {
 	return v
 }
 
-//Handle0x0 handler for Clear and Return instructions
-func Handle0x0(chip *Chip8, opcode uint16) {
+//Handle0x0 handler for Clear and Return instructions, along with the SUPER-CHIP
+//scroll, exit and display mode instructions and the XO-CHIP scroll up
+func Handle0x0(chip *Chip8, opcode uint16) error {
+	switch opcode & 0xFFF0 {
+	case ScrollDown:
+		chip.ScrollDown(int(opcode & nibbleMask))
+		return nil
+	case ScrollUp:
+		chip.ScrollUp(int(opcode & nibbleMask))
+		return nil
+	}
+
 	switch opcode {
 	case Clear:
 		chip.ClearScreenMem()
 	case Return:
-		chip.SetPc(chip.Pop())
+		addr, err := chip.Pop()
+		if err != nil {
+			return err
+		}
+		chip.SetPc(addr)
+	case ScrollRight:
+		chip.ScrollRight(4)
+	case ScrollLeft:
+		chip.ScrollLeft(4)
+	case Exit:
+		chip.Exit()
+	case LowRes:
+		chip.SetHiRes(false)
+	case HighRes:
+		chip.SetHiRes(true)
 	default:
+		return ErrInvalidOpcode
 	}
+	return nil
 }
 
 //Handle0x1 intruction: Jump to address specified in NNN
 //Opcode format 1NNN
-func Handle0x1(chip *Chip8, opcode uint16) {
+func Handle0x1(chip *Chip8, opcode uint16) error {
 	switch opcode & opMask {
 	case Jump:
 		val := opcode & mask12
 		chip.SetPc(val)
+	default:
+		return ErrInvalidOpcode
 	}
+	return nil
 }
 
 //Handle0x2 instruction: Jump Subroutine, pushes the current PC to the stack and
 //loads the PC with address specified with NNN
 //Opcode format: 2NNN
-func Handle0x2(chip *Chip8, opcode uint16) {
+func Handle0x2(chip *Chip8, opcode uint16) error {
 	switch (opcode & opMask) >> 12 {
 	case JumpSub >> 12:
-		chip.Push(chip.Pc)
+		if err := chip.Push(chip.Pc); err != nil {
+			return err
+		}
 		addr := opcode & mask12
 		chip.Pc = addr
 	default:
-		fmt.Println("ARSE")
+		return ErrInvalidOpcode
 	}
+	return nil
 }
 
 //Handle0x3 instruction Skip next instruction if Vx equals NN
 //Opcode format: 3XNN
-func Handle0x3(chip *Chip8, opcode uint16) {
+func Handle0x3(chip *Chip8, opcode uint16) error {
 	switch opcode & opMask >> 12 {
 	case SkipVxEqKk >> 12:
 		vx := GetRegVx(opcode)
 		kk := opcode & 0x00FF
 		val := chip.GetV(vx)
 		if val == uint8(kk) {
-			chip.Pc += 2
+			chip.skip()
 		}
+	default:
+		return ErrInvalidOpcode
 	}
+	return nil
 }
 
 //Handle0x4 instruction: Skip next instruction if Vx is not equal to value NN
 //Opcode format 4XNN
-func Handle0x4(chip *Chip8, opcode uint16) {
+func Handle0x4(chip *Chip8, opcode uint16) error {
 	switch opcode & opMask >> 12 {
 	case SkipVxNeqKk >> 12:
 		vx := GetRegVx(opcode)
 		kk := opcode & 0x00FF
 		val := chip.GetV(vx)
 		if val != uint8(kk) {
-			chip.Pc += 2
+			chip.skip()
 		}
+	default:
+		return ErrInvalidOpcode
 	}
+	return nil
 }
 
 //Handle0x5 instruction: Skip the next instruction if Vx equals Vy
 //Opcode format: 5XY0
-func Handle0x5(chip *Chip8, opcode uint16) {
-	switch opcode & opMask >> 12 {
-	case SkipVxEqVy >> 12:
+//Along with the XO-CHIP instructions to save and load the registers Vx to Vy
+//Opcode format: 5XY2, 5XY3
+func Handle0x5(chip *Chip8, opcode uint16) error {
+	switch opcode & 0xF00F {
+	case SkipVxEqVy:
 		vx := GetRegVx(opcode)
 		vy := GetRegVy(opcode)
 		r1 := chip.GetV(vx)
 		r2 := chip.GetV(vy)
 		if r1 == r2 {
-			chip.Pc += 2
+			chip.skip()
 		}
+	case SaveVxToVy:
+		return chip.SaveRange(GetRegVx(opcode), GetRegVy(opcode))
+	case LoadVxToVy:
+		return chip.LoadRange(GetRegVx(opcode), GetRegVy(opcode))
+	default:
+		return ErrInvalidOpcode
 	}
+	return nil
 }
 
 //Handle0x6 instruction: Load Vx from NN
 //Opcode format: 6XNN
-func Handle0x6(chip *Chip8, opcode uint16) {
+func Handle0x6(chip *Chip8, opcode uint16) error {
 	switch opcode & opMask >> 12 {
 	case LoadVxFromKk >> 12:
 		chip.SetV(GetRegVx(opcode), GetOpVal(opcode))
+	default:
+		return ErrInvalidOpcode
 	}
+	return nil
 }
 
 //Handle0x7 instruction: Load Vx with the result of Vx add NN
 //Opcode format: 7XNN
-func Handle0x7(chip *Chip8, opcode uint16) {
+func Handle0x7(chip *Chip8, opcode uint16) error {
 	switch opcode & opMask >> 12 {
 	case LoadVxAddKk >> 12:
 		vx := GetRegVx(opcode)
 		val := chip.GetV(vx)
 		chip.SetV(vx, val+GetOpVal(opcode))
+	default:
+		return ErrInvalidOpcode
 	}
+	return nil
+}
 
+//shiftSource get the value to be shifted by 8XY6/8XYE, Vy on the COSMAC VIP
+//otherwise Vx, see Quirks.ShiftVy
+func (c *Chip8) shiftSource(opcode uint16) uint8 {
+	if c.Quirks.ShiftVy {
+		return c.GetV(GetRegVy(opcode))
+	}
+	return c.GetV(GetRegVx(opcode))
+}
+
+//resetVF clears VF after the logical operations, if Quirks.VFReset is set
+func (c *Chip8) resetVF() {
+	if c.Quirks.VFReset {
+		c.SetV(VF, 0)
+	}
 }
 
 //Handle0x8 handler for various instructions:
@@ -208,7 +288,7 @@ func Handle0x7(chip *Chip8, opcode uint16) {
 //Instruction: Load Vx with result of Right-Shift Vx
 //Instruction: Load Vx with result of Vx Sub Vx
 //Instruction: Load Vx with result of Left-Shift Vx
-func Handle0x8(chip *Chip8, opcode uint16) {
+func Handle0x8(chip *Chip8, opcode uint16) error {
 	switch opcode & 0xF00F {
 	case LoadVxFromVy & 0xF00F:
 		chip.SetV(GetRegVx(opcode), chip.GetV(GetRegVy(opcode)))
@@ -216,14 +296,17 @@ func Handle0x8(chip *Chip8, opcode uint16) {
 		vx := chip.GetV(GetRegVx(opcode))
 		vy := chip.GetV(GetRegVy(opcode))
 		chip.SetV(GetRegVx(opcode), vx|vy)
+		chip.resetVF()
 	case LoadVxAndVy & 0xF00F:
 		vx := chip.GetV(GetRegVx(opcode))
 		vy := chip.GetV(GetRegVy(opcode))
 		chip.SetV(GetRegVx(opcode), vx&vy)
+		chip.resetVF()
 	case LoadVxXorVy & 0xF00F:
 		vx := chip.GetV(GetRegVx(opcode))
 		vy := chip.GetV(GetRegVy(opcode))
 		chip.SetV(GetRegVx(opcode), vx^vy)
+		chip.resetVF()
 	case LoadVxAddVy & 0xF00F:
 		vx := chip.GetV(GetRegVx(opcode))
 		vy := chip.GetV(GetRegVy(opcode))
@@ -241,7 +324,7 @@ func Handle0x8(chip *Chip8, opcode uint16) {
 		}
 		chip.SetV(GetRegVx(opcode), vx-vy)
 	case LoadVxShiftR & 0xF00F:
-		vx := chip.GetV(GetRegVx(opcode))
+		vx := chip.shiftSource(opcode)
 		chip.SetV(VF, vx&0x1)
 		chip.SetV(GetRegVx(opcode), vx>>1)
 	case LoadVxVySubVx & 0xF00F:
@@ -254,103 +337,150 @@ func Handle0x8(chip *Chip8, opcode uint16) {
 		}
 		chip.SetV(GetRegVx(opcode), vy-vx)
 	case LoadVxShiftL & 0xF00F:
-		vx := chip.GetV(GetRegVx(opcode))
+		vx := chip.shiftSource(opcode)
 		chip.SetV(VF, (vx&0x80)>>7)
 		chip.SetV(GetRegVx(opcode), vx<<1)
 	default:
-		fmt.Printf("Opcode: %v, Masked %v \n", opcode, LoadVxFromVy&0xF00F)
+		return ErrInvalidOpcode
 	}
+	return nil
 }
 
 //Handle0x9 Instruction: Skip if Vx != Vy
-func Handle0x9(chip *Chip8, opcode uint16) {
-	switch opcode & opMask >> 12 {
-	case SkipVxNeqVy >> 12:
+//Opcode format: 9XY0
+func Handle0x9(chip *Chip8, opcode uint16) error {
+	switch opcode & 0xF00F {
+	case SkipVxNeqVy:
 		vx := GetRegVx(opcode)
 		vy := GetRegVy(opcode)
 		r1 := chip.GetV(vx)
 		r2 := chip.GetV(vy)
 		if r1 != r2 {
-			chip.Pc += 2
+			chip.skip()
 		}
+	default:
+		return ErrInvalidOpcode
 	}
+	return nil
 }
 
 //Handle0xA instruction: Load Register I With Embedded Opcode Value NNN
 //Opcode format: ANNN
-func Handle0xA(chip *Chip8, opcode uint16) {
+func Handle0xA(chip *Chip8, opcode uint16) error {
 	switch opcode & opMask >> 12 {
 	case LoadIFromNnn >> 12:
 		val := GetOpVal12(opcode)
 		chip.SetI(val)
-
+	default:
+		return ErrInvalidOpcode
 	}
+	return nil
 }
 
-//Handle0xB instruction: Jump address V0 + NNN
+//Handle0xB instruction: Jump address V0 + NNN, or XNN + Vx with Quirks.JumpVx
 //Opcode format: BNNN
-func Handle0xB(chip *Chip8, opcode uint16) {
+func Handle0xB(chip *Chip8, opcode uint16) error {
 	switch opcode & opMask >> 12 {
 	case JumpPlusA0 >> 12:
 		val := GetOpVal12(opcode)
-		v0 := chip.GetV(0)
-		chip.SetPc(val + uint16(v0))
+		reg := uint8(V0)
+		if chip.Quirks.JumpVx {
+			reg = GetRegVx(opcode)
+		}
+		chip.SetPc(val + uint16(chip.GetV(reg)))
+	default:
+		return ErrInvalidOpcode
 	}
+	return nil
 }
 
 //Handle0xC instruction: Load Register Vx with a bitwise AND operation with NN and a random value between 0 - 255
 //Logic: Vx=rand() & NN
 //Opcode format: CXNN
-func Handle0xC(chip *Chip8, opcode uint16) {
+func Handle0xC(chip *Chip8, opcode uint16) error {
 	switch opcode & opMask >> 12 {
 	case RndVxAndKk >> 12:
 		val := GetOpVal(opcode)
-		r := rand.New(rand.NewSource(time.Now().UnixNano()))
-		rndVal := r.Intn(256)
+		rndVal := chip.rng.Intn(256)
 		chip.SetV(GetRegVx(opcode), uint8(rndVal)&val)
+	default:
+		return ErrInvalidOpcode
 	}
+	return nil
 }
 
-//Handle0xD instruction: Draw a Sprite to the screen at location Vx, Vy with height N
+//Handle0xD instruction: Draw a Sprite to the screen at location Vx, Vy with height N,
+//or a 16x16 SUPER-CHIP sprite when N is 0
 //Opcode format: DXYN
-func Handle0xD(chip *Chip8, opcode uint16) {
+func Handle0xD(chip *Chip8, opcode uint16) error {
 	switch opcode & opMask >> 12 {
 	case DrawSprite >> 12:
 		vx := GetRegVx(opcode)
 		vy := GetRegVy(opcode)
 		n := opcode & nibbleMask
+		size := int(n)
+		if n == 0 {
+			size = 32
+		}
+		size *= len(chip.selectedPlanes())
+		if err := chip.checkMem(chip.I, size); err != nil {
+			return err
+		}
 		chip.Draw(uint8(n), int32(chip.GetV(vx)), int32(chip.GetV(vy)))
+	default:
+		return ErrInvalidOpcode
 	}
+	return nil
 }
 
 //Handle0xE Handler for instructions relate to key presses
-func Handle0xE(chip *Chip8, opcode uint16) {
+func Handle0xE(chip *Chip8, opcode uint16) error {
 	switch opcode & opMask12 {
 	case SkipVxEqKey:
 		vx := GetRegVx(opcode)
-		v := chip.GetV(vx)
+		v := chip.GetV(vx) & nibbleMask
 		if chip.Keys[v] == 1 {
-			chip.Pc += 2
+			chip.skip()
 		}
 	case SkipVxNeqKey:
 		vx := GetRegVx(opcode)
-		v := chip.GetV(vx)
+		v := chip.GetV(vx) & nibbleMask
 		if chip.Keys[v] == 0 {
-			chip.Pc += 2
+			chip.skip()
 		}
-	case LoadVxFromK:
-		vx := GetRegVx(opcode)
-		in := chip.DisplayHandler.WaitForInput()
-		chip.SetV(vx, uint8(in))
+	default:
+		return ErrInvalidOpcode
 	}
+	return nil
 }
 
 //Handle0xF handler for various instructions.
-func Handle0xF(chip *Chip8, opcode uint16) {
+func Handle0xF(chip *Chip8, opcode uint16) error {
 	switch opcode & opMask12 {
+	case LoadILong:
+		if opcode != LoadILong {
+			return ErrInvalidOpcode
+		}
+		if err := chip.checkMem(chip.GetPc(), 2); err != nil {
+			return err
+		}
+		addr := uint16(chip.Memory[chip.Pc])<<8 | uint16(chip.Memory[chip.Pc+1])
+		chip.SetI(addr)
+		chip.SetPc(chip.Pc + 2)
+	case SelectPlane:
+		chip.SelectPlanes(GetRegVx(opcode))
+	case LoadAudioPattern:
+		if opcode != LoadAudioPattern {
+			return ErrInvalidOpcode
+		}
+		return chip.LoadAudioPattern()
+	case LoadPitchFromVx:
+		chip.Pitch = chip.GetV(GetRegVx(opcode))
 	case LoadVxFromDelayTimer:
 		vx := GetRegVx(opcode)
-		chip.SetV(vx, chip.DelayTimer)
+		chip.SetV(vx, chip.GetDT())
+	case LoadVxFromK:
+		chip.KeyWait = KeyWait{Active: true, Reg: GetRegVx(opcode)}
 	case LoadDelayTimerFromVx:
 		vx := GetRegVx(opcode)
 		chip.SetDT(chip.GetV(vx))
@@ -363,33 +493,66 @@ func Handle0xF(chip *Chip8, opcode uint16) {
 		chip.SetI(val)
 	case LoadSpriteCharacter:
 		vx := GetRegVx(opcode)
-		n := chip.GetV(vx)
+		n := chip.GetV(vx) & nibbleMask
 		chip.SetI(GetCharBank(uint16(n)))
+	case LoadBigSpriteChar:
+		vx := GetRegVx(opcode)
+		chip.SetI(GetBigCharBank(uint16(chip.GetV(vx))))
 	case LoadIWithBcdOfVx:
 		//Set I to BCD of Vx")
 		vx := GetRegVx(opcode)
 		n := chip.GetV(vx)
 		bcd := decimalToBcd(uint16(n))
 		idx := chip.GetI()
-		chip.Memory[idx] = uint8((bcd & 0x0F00) >> 8)
-		chip.Memory[idx+1] = uint8((bcd & 0x00F0) >> 4)
-		chip.Memory[idx+2] = uint8((bcd & 0x000F))
+		if err := chip.checkMem(idx, 3); err != nil {
+			return err
+		}
+		chip.writeMem(idx, uint8((bcd&0x0F00)>>8))
+		chip.writeMem(idx+1, uint8((bcd&0x00F0)>>4))
+		chip.writeMem(idx+2, uint8(bcd&0x000F))
 	case StoreV0ToVx:
 		vx := GetRegVx(opcode)
 		idx := chip.GetI()
+		if err := chip.checkMem(idx, int(vx)+1); err != nil {
+			return err
+		}
 		var i uint8
 		for i = 0; i <= vx; i++ {
-			chip.Memory[idx] = chip.GetV(i)
+			chip.writeMem(idx, chip.GetV(i))
 			idx++
 		}
+		if chip.Quirks.LoadStoreIncI {
+			chip.SetI(idx)
+		}
 	case LoadIToV0ToVx:
 		vx := GetRegVx(opcode)
 		idx := chip.GetI()
+		if err := chip.checkMem(idx, int(vx)+1); err != nil {
+			return err
+		}
 		var i uint8
 		for i = 0; i <= vx; i++ {
-			val := chip.Memory[idx]
+			val := chip.readMem(idx)
 			chip.SetV(i, val)
 			idx++
 		}
+		if chip.Quirks.LoadStoreIncI {
+			chip.SetI(idx)
+		}
+	case StoreV0ToVxInRPL:
+		vx := GetRegVx(opcode)
+		var i uint8
+		for i = 0; i <= vx; i++ {
+			chip.RPL[i] = chip.GetV(i)
+		}
+	case LoadRPLToV0ToVx:
+		vx := GetRegVx(opcode)
+		var i uint8
+		for i = 0; i <= vx; i++ {
+			chip.SetV(i, chip.RPL[i])
+		}
+	default:
+		return ErrInvalidOpcode
 	}
+	return nil
 }
@@ -0,0 +1,32 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+//Errors that can stop the FDE cycle
+var (
+	ErrInvalidOpcode     = errors.New("invalid opcode")
+	ErrStackOverflow     = errors.New("stack overflow")
+	ErrStackUnderflow    = errors.New("stack underflow")
+	ErrMemoryOutOfBounds = errors.New("memory access out of bounds")
+)
+
+//MachineError wraps an error raised while executing an instruction, recording the
+//address and opcode of the offending instruction.
+type MachineError struct {
+	Err    error
+	Pc     uint16
+	Opcode uint16
+}
+
+func (e *MachineError) Error() string {
+	return fmt.Sprintf("%v: opcode 0x%04X at 0x%03X", e.Err, e.Opcode, e.Pc)
+}
+
+//Unwrap returns the underlying error, so errors.Is can be used to test for
+//ErrInvalidOpcode etc.
+func (e *MachineError) Unwrap() error {
+	return e.Err
+}
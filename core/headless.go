@@ -0,0 +1,82 @@
+package core
+
+import (
+	"strings"
+	"time"
+)
+
+//HeadlessDisplay a display handler that renders nothing, allowing the machine to
+//run without a front end, e.g. in tests and batch tools.
+type HeadlessDisplay struct {
+	alive bool
+}
+
+//NewHeadlessDisplay constructor for a headless display, attaching it to the machine
+//along with null input and audio and a clock that never sleeps
+func NewHeadlessDisplay(cpu *Chip8) *HeadlessDisplay {
+	d := &HeadlessDisplay{alive: true}
+	cpu.DisplayHandler = d
+	cpu.InputHandler = NullInput{}
+	cpu.AudioHandler = NullAudio{}
+	cpu.Clock = NullClock{}
+	return d
+}
+
+//Render does nothing, there is nothing to render to
+func (d *HeadlessDisplay) Render() {}
+
+//IsAlive reports whether the display is still running
+func (d *HeadlessDisplay) IsAlive() bool {
+	return d.alive
+}
+
+//Shutdown stops the display, causing Start to return
+func (d *HeadlessDisplay) Shutdown() {
+	d.alive = false
+}
+
+//NullInput an input source that never has any keys pressed
+type NullInput struct{}
+
+//HandleInput does nothing, there is no input source
+func (NullInput) HandleInput() {}
+
+//NullAudio an audio output that discards all sound
+type NullAudio struct{}
+
+//Play does nothing
+func (NullAudio) Play() {}
+
+//Stop does nothing
+func (NullAudio) Stop() {}
+
+//NullClock a clock that never sleeps, running the machine as fast as possible
+type NullClock struct{}
+
+//Sleep returns immediately
+func (NullClock) Sleep(d time.Duration) {}
+
+//realClock the default clock, sleeping using the Go runtime
+type realClock struct{}
+
+func (realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+//screenChars the characters used by ScreenText for each pixel value, i.e. the
+//combination of XO-CHIP planes lit
+const screenChars = ".#o@"
+
+//ScreenText the display as text, one line per row in the current resolution, with
+//a . for each unlit pixel and a # for each lit one. On XO-CHIP pixels lit only in
+//plane 2 are shown as o and pixels lit in both as @.
+func (c *Chip8) ScreenText() string {
+	var s strings.Builder
+	for y := 0; y < c.ScreenHeight(); y++ {
+		for x := 0; x < c.ScreenWidth(); x++ {
+			s.WriteByte(screenChars[c.VMem[y][x]&0x3])
+		}
+		s.WriteByte('\n')
+	}
+	return s.String()
+}
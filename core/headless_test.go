@@ -0,0 +1,98 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testGames = []string{"BLINKY", "BRIX", "INVADERS", "MISSILE", "TETRIS", "UFO", "WIPEOFF"}
+
+func TestHeadlessRunFrames(t *testing.T) {
+	for _, game := range testGames {
+		chip := NewChip8()
+		NewHeadlessDisplay(chip)
+		chip.Load("../games/" + game)
+		err := chip.RunFrames(600)
+
+		if err == nil && chip.GetPc() >= 0x200 {
+			t.Log(game + " ran headless for 600 frames - Test Passed")
+		} else {
+			msg := fmt.Sprintf("%v: stopped at PC %x, error: %v", game, chip.GetPc(), err)
+			t.Error(msg)
+		}
+	}
+}
+
+//frameCountDisplay a headless display that shuts down after a number of frames
+type frameCountDisplay struct {
+	HeadlessDisplay
+	frames int
+}
+
+func (d *frameCountDisplay) Render() {
+	d.frames--
+	if d.frames == 0 {
+		d.Shutdown()
+	}
+}
+
+//sleepRecorder a clock that records the requested sleeps instead of sleeping
+type sleepRecorder struct {
+	sleeps []time.Duration
+}
+
+func (s *sleepRecorder) Sleep(d time.Duration) {
+	s.sleeps = append(s.sleeps, d)
+}
+
+func TestStartFramePacing(t *testing.T) {
+	chip := NewChip8()
+	NewHeadlessDisplay(chip)
+	display := &frameCountDisplay{HeadlessDisplay: HeadlessDisplay{alive: true}, frames: 3}
+	clock := &sleepRecorder{}
+	chip.DisplayHandler = display
+	chip.Clock = clock
+	chip.SetClockHz(600)
+	chip.SetMem(0x200, Jump|0x200)
+
+	if err := chip.Start(); err != nil {
+		t.Fatal("Start failed: ", err)
+	}
+
+	if chip.Cycles == 30 && len(clock.sleeps) == 3 && clock.sleeps[0] <= FrameDuration {
+		t.Log("Start runs 10 instructions per frame and sleeps between frames - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected 30 cycles and 3 sleeps, actual %v cycles, sleeps %v", chip.Cycles, clock.sleeps)
+		t.Error(msg)
+	}
+
+	chip.Turbo = true
+	display.alive, display.frames = true, 3
+	clock.sleeps = nil
+	chip.Start()
+
+	if len(clock.sleeps) == 0 {
+		t.Log("Turbo runs without sleeping - Test Passed")
+	} else {
+		t.Error("Expected no sleeps in turbo, actual ", clock.sleeps)
+	}
+}
+
+func TestScreenText(t *testing.T) {
+	chip := NewChip8()
+	NewHeadlessDisplay(chip)
+	chip.SetMem(0x200, LoadVxFromKk|0x00A) //V0 = 10, the font sprite for A
+	chip.SetMem(0x202, LoadSpriteCharacter)
+	chip.SetMem(0x204, DrawSprite|0x115) //at V1, V1 = 0, 0
+	chip.RunCycles(3)
+
+	lines := strings.Split(chip.ScreenText(), "\n")
+	if len(lines) == 33 && lines[0][:8] == "####...." && lines[1][:8] == "#..#...." && len(lines[0]) == 64 {
+		t.Log("Screen as text - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected screen text:\n%s", chip.ScreenText())
+		t.Error(msg)
+	}
+}
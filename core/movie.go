@@ -0,0 +1,173 @@
+package core
+
+import (
+	"chip8emu/utils"
+	"crypto/sha256"
+	"errors"
+)
+
+//ErrMovieDesync returned when a movie's playback doesn't end on the display it was
+//recorded with
+var ErrMovieDesync = errors.New("movie desynchronised, the final display differs from the recording")
+
+//VMemHash the SHA-256 of the video memory, used to check that a movie played back
+//to the same display as when it was recorded
+func (c *Chip8) VMemHash() [32]byte {
+	h := sha256.New()
+	for _, row := range c.VMem {
+		h.Write(row[:])
+	}
+	var sum [32]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+//keyBits the pressed keys as a bit set, bit n set for key n
+func (c *Chip8) keyBits() uint16 {
+	var bits uint16
+	for key, pressed := range c.Keys {
+		if pressed != 0 {
+			bits |= 1 << uint(key)
+		}
+	}
+	return bits
+}
+
+//setKeyBits sets the pressed keys from a bit set
+func (c *Chip8) setKeyBits(bits uint16) {
+	for key := range c.Keys {
+		c.Keys[key] = uint8(bits >> uint(key) & 1)
+	}
+}
+
+//MovieRecorder records the machine's input as a movie, by wrapping its input
+//handler. Everything else the machine does follows from the input, so the movie
+//reproduces the session as long as the debugger and save states aren't used.
+type MovieRecorder struct {
+	InputInterface
+	cpu   *Chip8
+	movie utils.Movie
+}
+
+//RecordMovie starts recording the machine's input, which must be done before the
+//machine starts. rom the SHA-1 of the ROM being run.
+func (c *Chip8) RecordMovie(rom [20]byte) *MovieRecorder {
+	//Restart the random number generator, so the seed describes its whole sequence
+	c.SeedRandom(c.Seed)
+	r := &MovieRecorder{InputInterface: c.InputHandler, cpu: c}
+	r.movie.MovieHeader = utils.MovieHeader{
+		Seed:           c.Seed,
+		Quirks:         c.Quirks.bits(),
+		CyclesPerFrame: uint16(c.CyclesPerFrame),
+		ROM:            rom,
+	}
+	c.InputHandler = r
+	return r
+}
+
+//HandleInput handles input on the wrapped input handler, then records the
+//resulting keys and speed
+func (r *MovieRecorder) HandleInput() {
+	r.InputInterface.HandleInput()
+	r.movie.Frames = append(r.movie.Frames, utils.MovieFrame{
+		Keys:           r.cpu.keyBits(),
+		CyclesPerFrame: uint16(r.cpu.CyclesPerFrame),
+	})
+}
+
+//Frames the number of frames recorded so far
+func (r *MovieRecorder) Frames() int {
+	return len(r.movie.Frames)
+}
+
+//Finish stops recording, restoring the machine's input handler, and returns the
+//movie ending on the current display
+func (r *MovieRecorder) Finish() *utils.Movie {
+	if r.cpu.InputHandler == r {
+		r.cpu.InputHandler = r.InputInterface
+	}
+	r.movie.VMemHash = r.cpu.VMemHash()
+	return &r.movie
+}
+
+//MoviePlayer plays back a movie as the machine's input. The wrapped input handler
+//still handles input each frame, so the front end stays responsive, but the keys
+//and speed are replaced with those recorded. Once the movie ends the display is
+//checked and the wrapped input handler takes over.
+type MoviePlayer struct {
+	input InputInterface
+	cpu   *Chip8
+	movie *utils.Movie
+	//frame the frame being played
+	frame int
+	done  bool
+	err   error
+
+	//OnEnd if set, called once the movie has ended with the result of the check
+	OnEnd func(err error)
+}
+
+//PlayMovie sets the machine up as the movie was recorded and starts playing it
+//back, which must be done before the machine starts
+func (c *Chip8) PlayMovie(m *utils.Movie) *MoviePlayer {
+	c.SeedRandom(m.Seed)
+	c.Quirks = quirksFromBits(m.Quirks)
+	c.SetCyclesPerFrame(int(m.CyclesPerFrame))
+	c.Keys = [16]uint8{}
+
+	p := &MoviePlayer{input: c.InputHandler, cpu: c, movie: m}
+	c.InputHandler = p
+	if len(m.Frames) == 0 {
+		p.finish(nil)
+	}
+	return p
+}
+
+//HandleInput plays back the next frame's input. The last frame's input is that
+//handled as the recording stopped, so instead of being played the display is
+//checked against the recording.
+func (p *MoviePlayer) HandleInput() {
+	p.input.HandleInput()
+	if p.done {
+		return
+	}
+
+	f := p.movie.Frames[p.frame]
+	p.frame++
+	if p.frame == len(p.movie.Frames) {
+		p.finish(nil)
+		return
+	}
+	p.cpu.setKeyBits(f.Keys)
+	p.cpu.SetCyclesPerFrame(int(f.CyclesPerFrame))
+}
+
+//finish ends playback, checking the display unless it has already failed, and
+//hands over to the wrapped input handler
+func (p *MoviePlayer) finish(err error) {
+	if err == nil && p.cpu.VMemHash() != p.movie.VMemHash {
+		err = ErrMovieDesync
+	}
+	p.done, p.err = true, err
+	if p.cpu.InputHandler == p {
+		p.cpu.InputHandler = p.input
+	}
+	if p.OnEnd != nil {
+		p.OnEnd(err)
+	}
+}
+
+//Done reports whether the movie has ended
+func (p *MoviePlayer) Done() bool {
+	return p.done
+}
+
+//Err the result of checking the playback, nil if it matched the recording
+func (p *MoviePlayer) Err() error {
+	return p.err
+}
+
+//Frame the number of frames played so far
+func (p *MoviePlayer) Frame() int {
+	return p.frame
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"chip8emu/utils"
+	"fmt"
+	"testing"
+)
+
+//scriptedInput presses a key that changes every few frames, as a player might
+type scriptedInput struct {
+	cpu   *Chip8
+	frame int
+}
+
+func (s *scriptedInput) HandleInput() {
+	s.frame++
+	s.cpu.Keys = [16]uint8{}
+	switch s.frame / 20 % 3 {
+	case 0:
+		s.cpu.SetKey(ChipKey4)
+	case 1:
+		s.cpu.SetKey(ChipKey6)
+	}
+	if s.frame == 100 {
+		s.cpu.SetCyclesPerFrame(12)
+	}
+}
+
+//recordBrix records a movie of BRIX played by the scripted input
+func recordBrix(frames int) (*utils.Movie, [32]byte) {
+	chip := NewChip8()
+	NewHeadlessDisplay(chip)
+	chip.DisplayHandler = &frameCountDisplay{HeadlessDisplay: HeadlessDisplay{alive: true}, frames: frames}
+	chip.Clock = &sleepRecorder{}
+	chip.InputHandler = &scriptedInput{cpu: chip}
+	chip.Load("../games/BRIX")
+
+	recorder := chip.RecordMovie([20]byte{})
+	chip.Start()
+	return recorder.Finish(), chip.VMemHash()
+}
+
+//playBrix plays back a movie of BRIX headless
+func playBrix(m *utils.Movie) (*Chip8, *MoviePlayer) {
+	chip := NewChip8()
+	NewHeadlessDisplay(chip)
+	chip.Load("../games/BRIX")
+
+	player := chip.PlayMovie(m)
+	for !player.Done() {
+		chip.RunFrames(1)
+		chip.InputHandler.HandleInput()
+	}
+	return chip, player
+}
+
+func TestMovieReplay(t *testing.T) {
+	movie, hash := recordBrix(300)
+	chip, player := playBrix(movie)
+
+	if len(movie.Frames) == 300 && player.Err() == nil && player.Frame() == 300 && chip.VMemHash() == hash && chip.CyclesPerFrame == 12 {
+		t.Log("Movie played back to the recorded display - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected 300 frames played to the recorded display, actual %d of %d frames, error %v",
+			player.Frame(), len(movie.Frames), player.Err())
+		t.Error(msg)
+	}
+	if _, isNull := chip.InputHandler.(NullInput); isNull {
+		t.Log("Input handed back once the movie ended - Test Passed")
+	} else {
+		t.Error("Expected the input handler to be restored, actual ", chip.InputHandler)
+	}
+
+	for i := range movie.Frames {
+		movie.Frames[i].Keys = 0
+	}
+	if _, player := playBrix(movie); player.Err() == ErrMovieDesync {
+		t.Log("Movie with different input desynchronised - Test Passed")
+	} else {
+		t.Error("Expected ErrMovieDesync, actual ", player.Err())
+	}
+}
+
+func TestMovieKeyWait(t *testing.T) {
+	//A program waiting for a key on its first frame, then stopping
+	newMachine := func() *Chip8 {
+		chip := NewChip8()
+		NewHeadlessDisplay(chip)
+		chip.SetMem(0x200, LoadVxFromK|0x500) //V5 = key
+		chip.SetMem(0x202, Jump|0x202)
+		return chip
+	}
+
+	//The scripted input holds 4 until frame 20
+	chip := newMachine()
+	chip.InputHandler = &scriptedInput{cpu: chip}
+	recorder := chip.RecordMovie([20]byte{})
+	for i := 0; i < 30; i++ {
+		chip.RunFrames(1)
+		chip.InputHandler.HandleInput()
+	}
+	movie := recorder.Finish()
+
+	chip = newMachine()
+	player := chip.PlayMovie(movie)
+	for !player.Done() {
+		chip.RunFrames(1)
+		chip.InputHandler.HandleInput()
+	}
+
+	if player.Err() == nil && chip.V[V5] == ChipKey4 && !chip.KeyWait.Active {
+		t.Log("Key waited for played back - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected V5 = %X, actual %X, wait %+v, error %v", ChipKey4, chip.V[V5], chip.KeyWait, player.Err())
+		t.Error(msg)
+	}
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//Quirks selects between the behaviours of the various Chip-8 interpreters where
+//they disagree on the semantics of an instruction.
+type Quirks struct {
+	//ShiftVy 8XY6/8XYE load Vx with Vy shifted, as on the COSMAC VIP, rather than
+	//shifting Vx in place.
+	ShiftVy bool
+	//JumpVx BNNN jumps to XNN + Vx, as on the CHIP-48 and SCHIP, rather than NNN + V0.
+	JumpVx bool
+	//LoadStoreIncI FX55/FX65 leave I pointing past the last register transferred.
+	LoadStoreIncI bool
+	//VFReset 8XY1/8XY2/8XY3 reset VF to 0.
+	VFReset bool
+	//Wrap sprites drawn past the edge of the screen wrap around to the other side,
+	//rather than being clipped.
+	Wrap bool
+}
+
+//Named quirks profiles
+const (
+	QuirksVIP    = "vip"
+	QuirksChip48 = "chip48"
+	QuirksSChip  = "schip"
+	QuirksModern = "modern"
+	QuirksXOChip = "xochip"
+)
+
+var quirksProfiles = map[string]Quirks{
+	QuirksVIP:    {ShiftVy: true, LoadStoreIncI: true, VFReset: true},
+	QuirksChip48: {JumpVx: true},
+	QuirksSChip:  {JumpVx: true},
+	QuirksModern: {},
+	QuirksXOChip: {ShiftVy: true, LoadStoreIncI: true, Wrap: true},
+}
+
+//QuirksProfile get the quirks for the named profile, see QuirksProfiles
+func QuirksProfile(name string) (Quirks, error) {
+	q, exists := quirksProfiles[strings.ToLower(name)]
+	if !exists {
+		return Quirks{}, fmt.Errorf("unknown quirks profile %q, expected one of %v", name, QuirksProfiles())
+	}
+	return q, nil
+}
+
+//QuirksProfiles get the names of the available quirks profiles
+func QuirksProfiles() []string {
+	names := make([]string, 0, len(quirksProfiles))
+	for name := range quirksProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//Set overrides a single quirk given as name=on|off, e.g. "shift-vy=on". A bare
+//name switches the quirk on.
+func (q *Quirks) Set(spec string) error {
+	name, val := spec, "on"
+	if i := strings.Index(spec, "="); i >= 0 {
+		name, val = spec[:i], spec[i+1:]
+	}
+
+	var on bool
+	switch strings.ToLower(val) {
+	case "on", "true", "1":
+		on = true
+	case "off", "false", "0":
+		on = false
+	default:
+		return fmt.Errorf("invalid value %q for quirk %q, expected on or off", val, name)
+	}
+
+	switch strings.ToLower(name) {
+	case "shift-vy":
+		q.ShiftVy = on
+	case "jump-vx":
+		q.JumpVx = on
+	case "load-store-inc-i":
+		q.LoadStoreIncI = on
+	case "vf-reset":
+		q.VFReset = on
+	case "wrap":
+		q.Wrap = on
+	default:
+		return fmt.Errorf("unknown quirk %q", name)
+	}
+	return nil
+}
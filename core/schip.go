@@ -0,0 +1,121 @@
+package core
+
+//SUPER-CHIP display dimensions
+const (
+	hiresWidth  = 128
+	hiresHeight = 64
+)
+
+//BigCharBank the address of the SUPER-CHIP 8x10 font, stored after the 4x5 font
+const BigCharBank = 0x50
+
+//bigCharHeight the number of bytes in each big font character
+const bigCharHeight = 10
+
+//SUPER-CHIP 8x10 font, 0-9 as per SCHIP 1.1, A-F as provided by Octo
+var bigChars = []uint8{
+	0x3C, 0x7E, 0xE7, 0xC3, 0xC3, 0xC3, 0xC3, 0xE7, 0x7E, 0x3C,
+	0x18, 0x38, 0x58, 0x18, 0x18, 0x18, 0x18, 0x18, 0x18, 0x3C,
+	0x3E, 0x7F, 0xC3, 0x06, 0x0C, 0x18, 0x30, 0x60, 0xFF, 0xFF,
+	0x3C, 0x7E, 0xC3, 0x03, 0x0E, 0x0E, 0x03, 0xC3, 0x7E, 0x3C,
+	0x06, 0x0E, 0x1E, 0x36, 0x66, 0xC6, 0xFF, 0xFF, 0x06, 0x06,
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFC, 0xFE, 0x03, 0xC3, 0x7E, 0x3C,
+	0x3E, 0x7C, 0xE0, 0xC0, 0xFC, 0xFE, 0xC3, 0xC3, 0x7E, 0x3C,
+	0xFF, 0xFF, 0x03, 0x06, 0x0C, 0x18, 0x30, 0x60, 0x60, 0x60,
+	0x3C, 0x7E, 0xC3, 0xC3, 0x7E, 0x7E, 0xC3, 0xC3, 0x7E, 0x3C,
+	0x3C, 0x7E, 0xC3, 0xC3, 0x7F, 0x3F, 0x03, 0x03, 0x3E, 0x7C,
+	0x7E, 0xFF, 0xC3, 0xC3, 0xC3, 0xFF, 0xFF, 0xC3, 0xC3, 0xC3,
+	0xFC, 0xFC, 0xC3, 0xC3, 0xFC, 0xFC, 0xC3, 0xC3, 0xFC, 0xFC,
+	0x3C, 0xFF, 0xC3, 0xC0, 0xC0, 0xC0, 0xC0, 0xC3, 0xFF, 0x3C,
+	0xFC, 0xFE, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xFE, 0xFC,
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF,
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0xC0, 0xC0, 0xC0, 0xC0,
+}
+
+//GetBigCharBank get the big font character address for the specified number
+func GetBigCharBank(n uint16) uint16 {
+	return BigCharBank + (n&nibbleMask)*bigCharHeight
+}
+
+//ScreenWidth get the width of the display in the current resolution
+func (c *Chip8) ScreenWidth() int {
+	if c.HiRes {
+		return hiresWidth
+	}
+	return displayWidth
+}
+
+//ScreenHeight get the height of the display in the current resolution
+func (c *Chip8) ScreenHeight() int {
+	if c.HiRes {
+		return hiresHeight
+	}
+	return displayHeight
+}
+
+//SetHiRes switches between the 64x32 and 128x64 display modes, clearing the screen
+func (c *Chip8) SetHiRes(on bool) {
+	c.HiRes = on
+	c.ClearScreenMem()
+}
+
+//ScrollDown scrolls the display down by n pixels
+func (c *Chip8) ScrollDown(n int) {
+	w, h := c.ScreenWidth(), c.ScreenHeight()
+	for y := h - 1; y >= 0; y-- {
+		for x := 0; x < w; x++ {
+			if y-n >= 0 {
+				c.scrollPixel(&c.VMem[y][x], c.VMem[y-n][x])
+			} else {
+				c.scrollPixel(&c.VMem[y][x], 0)
+			}
+		}
+	}
+}
+
+//ScrollUp scrolls the display up by n pixels, an XO-CHIP extension
+func (c *Chip8) ScrollUp(n int) {
+	w, h := c.ScreenWidth(), c.ScreenHeight()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if y+n < h {
+				c.scrollPixel(&c.VMem[y][x], c.VMem[y+n][x])
+			} else {
+				c.scrollPixel(&c.VMem[y][x], 0)
+			}
+		}
+	}
+}
+
+//ScrollRight scrolls the display right by n pixels
+func (c *Chip8) ScrollRight(n int) {
+	w, h := c.ScreenWidth(), c.ScreenHeight()
+	for y := 0; y < h; y++ {
+		for x := w - 1; x >= 0; x-- {
+			if x-n >= 0 {
+				c.scrollPixel(&c.VMem[y][x], c.VMem[y][x-n])
+			} else {
+				c.scrollPixel(&c.VMem[y][x], 0)
+			}
+		}
+	}
+}
+
+//ScrollLeft scrolls the display left by n pixels
+func (c *Chip8) ScrollLeft(n int) {
+	w, h := c.ScreenWidth(), c.ScreenHeight()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x+n < w {
+				c.scrollPixel(&c.VMem[y][x], c.VMem[y][x+n])
+			} else {
+				c.scrollPixel(&c.VMem[y][x], 0)
+			}
+		}
+	}
+}
+
+//Exit halts the machine, Start and RunCycles return once halted
+func (c *Chip8) Exit() {
+	c.Halted = true
+}
@@ -0,0 +1,106 @@
+package core
+
+import (
+	"chip8emu/utils"
+	"io"
+)
+
+//Quirks bits, as stored in a save state
+const (
+	quirkShiftVy = 1 << iota
+	quirkJumpVx
+	quirkLoadStoreIncI
+	quirkVFReset
+	quirkWrap
+)
+
+func (q Quirks) bits() uint8 {
+	var b uint8
+	if q.ShiftVy {
+		b |= quirkShiftVy
+	}
+	if q.JumpVx {
+		b |= quirkJumpVx
+	}
+	if q.LoadStoreIncI {
+		b |= quirkLoadStoreIncI
+	}
+	if q.VFReset {
+		b |= quirkVFReset
+	}
+	if q.Wrap {
+		b |= quirkWrap
+	}
+	return b
+}
+
+func quirksFromBits(b uint8) Quirks {
+	return Quirks{
+		ShiftVy:       b&quirkShiftVy != 0,
+		JumpVx:        b&quirkJumpVx != 0,
+		LoadStoreIncI: b&quirkLoadStoreIncI != 0,
+		VFReset:       b&quirkVFReset != 0,
+		Wrap:          b&quirkWrap != 0,
+	}
+}
+
+//SaveState writes a snapshot of the machine to w, see utils.MachineState
+func (c *Chip8) SaveState(w io.Writer) error {
+	s := &utils.MachineState{
+		Memory:        c.Memory,
+		V:             c.V,
+		I:             c.I,
+		Pc:            c.Pc,
+		S:             c.S,
+		Sp:            c.Sp,
+		DelayTimer:    c.DelayTimer,
+		SoundTimer:    c.SoundTimer,
+		Cycles:        c.Cycles,
+		Keys:          c.Keys,
+		KeyWait:       c.KeyWait.Active,
+		KeyWaitReg:    c.KeyWait.Reg,
+		KeyWaitHeld:   c.KeyWait.Pressed,
+		KeyWaitKey:    c.KeyWait.Key,
+		VMem:          c.VMem,
+		HiRes:         c.HiRes,
+		Halted:        c.Halted,
+		RPL:           c.RPL,
+		Planes:        c.Planes,
+		AudioPattern:  c.AudioPattern,
+		Pitch:         c.Pitch,
+		PatternLoaded: c.PatternLoaded,
+		Quirks:        c.Quirks.bits(),
+	}
+	return s.Encode(w)
+}
+
+//LoadState restores a snapshot written by SaveState. The machine is left unchanged
+//if the snapshot can't be read.
+func (c *Chip8) LoadState(r io.Reader) error {
+	s := &utils.MachineState{}
+	if err := s.Decode(r); err != nil {
+		return err
+	}
+
+	c.Memory = s.Memory
+	c.V = s.V
+	c.I = s.I
+	c.Pc = s.Pc
+	c.S = s.S
+	c.Sp = s.Sp
+	c.DelayTimer = s.DelayTimer
+	c.SoundTimer = s.SoundTimer
+	c.Cycles = s.Cycles
+	c.Keys = s.Keys
+	c.KeyWait = KeyWait{Active: s.KeyWait, Reg: s.KeyWaitReg, Pressed: s.KeyWaitHeld, Key: s.KeyWaitKey}
+	c.VMem = s.VMem
+	c.HiRes = s.HiRes
+	c.Halted = s.Halted
+	c.RPL = s.RPL
+	c.Planes = s.Planes
+	c.AudioPattern = s.AudioPattern
+	c.Pitch = s.Pitch
+	c.PatternLoaded = s.PatternLoaded
+	c.Quirks = quirksFromBits(s.Quirks)
+	return nil
+}
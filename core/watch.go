@@ -0,0 +1,24 @@
+package core
+
+import "chip8emu/utils"
+
+//watch checks an access made by the current instruction against the machine
+//monitor's watchpoints
+func (c *Chip8) watch(kind utils.WatchKind, access utils.WatchAccess, index, old, new uint16) {
+	if c.MM.HasWatches() {
+		c.MM.CheckWatch(kind, access, index, old, new, c.instPc, c.instOpcode)
+	}
+}
+
+//readMem reads a byte of memory on behalf of an instruction
+func (c *Chip8) readMem(addr uint16) uint8 {
+	val := c.Memory[addr]
+	c.watch(utils.WatchMem, utils.WatchRead, addr, uint16(val), uint16(val))
+	return val
+}
+
+//writeMem writes a byte of memory on behalf of an instruction
+func (c *Chip8) writeMem(addr uint16, val uint8) {
+	c.watch(utils.WatchMem, utils.WatchWrite, addr, uint16(c.Memory[addr]), uint16(val))
+	c.Memory[addr] = val
+}
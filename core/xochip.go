@@ -0,0 +1,116 @@
+package core
+
+import "math"
+
+//XO-CHIP bitplanes, VMem holds a mask of the planes set for each pixel
+const (
+	Plane1    = 0x1
+	Plane2    = 0x2
+	AllPlanes = Plane1 | Plane2
+)
+
+//DefaultPitch the XO-CHIP pitch giving a 4000Hz pattern playback rate
+const DefaultPitch = 64
+
+//skip skips the next instruction, stepping over the whole of a 4 byte XO-CHIP
+//F000 NNNN instruction.
+func (c *Chip8) skip() {
+	if int(c.Pc)+1 < len(c.Memory) && c.Memory[c.Pc] == 0xF0 && c.Memory[c.Pc+1] == 0x00 {
+		c.Pc += 4
+		return
+	}
+	c.Pc += 2
+}
+
+//SelectPlanes selects the bitplanes affected by drawing, clearing and scrolling
+func (c *Chip8) SelectPlanes(planes uint8) {
+	c.Planes = planes & AllPlanes
+}
+
+//selectedPlanes get the selected planes, in drawing order
+func (c *Chip8) selectedPlanes() []uint8 {
+	planes := make([]uint8, 0, 2)
+	for _, p := range []uint8{Plane1, Plane2} {
+		if c.Planes&p != 0 {
+			planes = append(planes, p)
+		}
+	}
+	return planes
+}
+
+//scrollPixel moves the selected planes of a pixel from src to dst
+func (c *Chip8) scrollPixel(dst *uint8, src uint8) {
+	*dst = (*dst &^ c.Planes) | (src & c.Planes)
+}
+
+//SaveRange stores registers Vx to Vy, in either direction, in memory starting at I
+func (c *Chip8) SaveRange(x, y uint8) error {
+	if err := c.checkMem(c.I, rangeLen(x, y)); err != nil {
+		return err
+	}
+	idx := c.GetI()
+	for _, r := range regRange(x, y) {
+		c.writeMem(idx, c.GetV(r))
+		idx++
+	}
+	return nil
+}
+
+//LoadRange loads registers Vx to Vy, in either direction, from memory starting at I
+func (c *Chip8) LoadRange(x, y uint8) error {
+	if err := c.checkMem(c.I, rangeLen(x, y)); err != nil {
+		return err
+	}
+	idx := c.GetI()
+	for _, r := range regRange(x, y) {
+		c.SetV(r, c.readMem(idx))
+		idx++
+	}
+	return nil
+}
+
+func rangeLen(x, y uint8) int {
+	if x > y {
+		return int(x-y) + 1
+	}
+	return int(y-x) + 1
+}
+
+func regRange(x, y uint8) []uint8 {
+	regs := make([]uint8, 0, rangeLen(x, y))
+	step := 1
+	if x > y {
+		step = -1
+	}
+	for r := int(x); ; r += step {
+		regs = append(regs, uint8(r))
+		if r == int(y) {
+			break
+		}
+	}
+	return regs
+}
+
+//LoadAudioPattern loads the 16 byte audio pattern buffer from memory at I
+func (c *Chip8) LoadAudioPattern() error {
+	if err := c.checkMem(c.I, len(c.AudioPattern)); err != nil {
+		return err
+	}
+	idx := c.GetI()
+	for i := range c.AudioPattern {
+		c.AudioPattern[i] = c.readMem(idx + uint16(i))
+	}
+	c.PatternLoaded = true
+	return nil
+}
+
+//PatternRate get the playback rate of the audio pattern in bits per second
+func (c *Chip8) PatternRate() float64 {
+	return 4000 * math.Pow(2, (float64(c.Pitch)-64)/48)
+}
+
+//PatternBit get bit n of the audio pattern buffer, wrapping at the end
+func (c *Chip8) PatternBit(n int) bool {
+	n %= len(c.AudioPattern) * 8
+	return c.AudioPattern[n/8]&(0x80>>uint(n%8)) != 0
+}
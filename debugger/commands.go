@@ -0,0 +1,385 @@
+package debugger
+
+import (
+	"chip8emu/core"
+	"chip8emu/utils"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+//command a debugger command, usage lists its arguments
+type command struct {
+	names []string
+	usage string
+	help  string
+	run   func(d *Debugger, args []string) error
+}
+
+//commandList the commands in the order they're listed by help, populated by init as
+//help refers back to it
+var commandList []*command
+
+//commands maps every command name and alias to its command
+var commands = map[string]*command{}
+
+func init() {
+	commandList = []*command{
+		{[]string{"break", "b"}, "<addr>", "Set a breakpoint", (*Debugger).cmdBreak},
+		{[]string{"delete", "d"}, "<addr>", "Delete a breakpoint", (*Debugger).cmdDelete},
+		{[]string{"watch", "w"}, "<target> [read|write|rw]", "Watch mem (addr or addr-addr), v0-vf, i, dt or st, write by default", (*Debugger).cmdWatch},
+		{[]string{"unwatch", "uw"}, "<id>", "Delete a watchpoint", (*Debugger).cmdUnwatch},
+		{[]string{"list", "l"}, "", "List breakpoints and watchpoints", (*Debugger).cmdList},
+		{[]string{"pause", "p"}, "", "Stop the machine", (*Debugger).cmdPause},
+		{[]string{"step", "s"}, "[n]", "Execute n instructions, 1 by default", (*Debugger).cmdStep},
+		{[]string{"continue", "c"}, "", "Resume execution", (*Debugger).cmdContinue},
+		{[]string{"registers", "r"}, "", "Show the registers and timers", (*Debugger).cmdRegisters},
+		{[]string{"mem", "m"}, "<addr> [len]", "Dump len bytes of memory, 64 by default", (*Debugger).cmdMem},
+		{[]string{"disasm", "u"}, "[addr] [n]", "Disassemble n instructions from addr, around PC by default", (*Debugger).cmdDisasm},
+		{[]string{"set"}, "<reg|addr> <value>...", "Set a register (v0-vf, i, pc, sp, dt, st) or memory bytes", (*Debugger).cmdSet},
+		{[]string{"backtrace", "bt"}, "", "Show the subroutine call stack", (*Debugger).cmdBacktrace},
+		{[]string{"help", "h", "?"}, "", "Show this help", (*Debugger).cmdHelp},
+	}
+
+	for _, cmd := range commandList {
+		for _, name := range cmd.names {
+			commands[name] = cmd
+		}
+	}
+}
+
+//errUsage returned when a command is given the wrong arguments
+var errUsage = errors.New("invalid arguments, type help for usage")
+
+func (d *Debugger) cmdBreak(args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	addr, err := parseAddr(args[0])
+	if err != nil {
+		return err
+	}
+	d.cpu.MM.SetBP(addr)
+	fmt.Fprintf(d.out, "Breakpoint set at 0x%03X\n", addr)
+	return nil
+}
+
+func (d *Debugger) cmdDelete(args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	addr, err := parseAddr(args[0])
+	if err != nil {
+		return err
+	}
+	if !d.cpu.MM.IsBP(addr) {
+		return fmt.Errorf("no breakpoint at 0x%03X", addr)
+	}
+	d.cpu.MM.ClrBP(addr)
+	fmt.Fprintf(d.out, "Breakpoint deleted at 0x%03X\n", addr)
+	return nil
+}
+
+func (d *Debugger) cmdWatch(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errUsage
+	}
+	w, err := parseWatchTarget(strings.ToLower(args[0]))
+	if err != nil {
+		return err
+	}
+
+	w.Access = utils.WatchWrite
+	if len(args) > 1 {
+		switch strings.ToLower(args[1]) {
+		case "read", "r":
+			w.Access = utils.WatchRead
+		case "write", "w":
+			w.Access = utils.WatchWrite
+		case "rw":
+			w.Access = utils.WatchReadWrite
+		default:
+			return fmt.Errorf("invalid access %q, expected read, write or rw", args[1])
+		}
+	}
+
+	w.ID = d.cpu.MM.AddWatch(w)
+	fmt.Fprintf(d.out, "Watchpoint %d: %s\n", w.ID, &w)
+	return nil
+}
+
+//parseWatchTarget parses the state a watchpoint is placed on: a memory address or
+//address range, a V register or register range, i, dt or st
+func parseWatchTarget(target string) (utils.Watchpoint, error) {
+	switch target {
+	case "i":
+		return utils.Watchpoint{Kind: utils.WatchI}, nil
+	case "dt":
+		return utils.Watchpoint{Kind: utils.WatchDT}, nil
+	case "st":
+		return utils.Watchpoint{Kind: utils.WatchST}, nil
+	}
+
+	start, end := target, target
+	if i := strings.Index(target, "-"); i >= 0 {
+		start, end = target[:i], target[i+1:]
+	}
+	if start == "" || end == "" {
+		return utils.Watchpoint{}, fmt.Errorf("invalid watch target %q", target)
+	}
+
+	if start[0] == 'v' && end[0] == 'v' {
+		first, err1 := parseReg(start)
+		last, err2 := parseReg(end)
+		if err1 != nil || err2 != nil || last < first {
+			return utils.Watchpoint{}, fmt.Errorf("invalid register range %q", target)
+		}
+		return utils.Watchpoint{Kind: utils.WatchV, Start: uint16(first), End: uint16(last)}, nil
+	}
+
+	first, err := parseAddr(start)
+	if err != nil {
+		return utils.Watchpoint{}, err
+	}
+	last, err := parseAddr(end)
+	if err != nil {
+		return utils.Watchpoint{}, err
+	}
+	if last < first {
+		return utils.Watchpoint{}, fmt.Errorf("invalid address range %q", target)
+	}
+	return utils.Watchpoint{Kind: utils.WatchMem, Start: first, End: last}, nil
+}
+
+func (d *Debugger) cmdUnwatch(args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	id, err := parseNum(args[0], 32)
+	if err != nil {
+		return fmt.Errorf("invalid watchpoint %q", args[0])
+	}
+	if !d.cpu.MM.DelWatch(int(id)) {
+		return fmt.Errorf("no watchpoint %d", id)
+	}
+	fmt.Fprintf(d.out, "Watchpoint %d deleted\n", id)
+	return nil
+}
+
+func (d *Debugger) cmdList(args []string) error {
+	bps := d.cpu.MM.BPs()
+	watches := d.cpu.MM.Watches()
+	if len(bps) == 0 && len(watches) == 0 {
+		fmt.Fprintln(d.out, "No breakpoints or watchpoints")
+	}
+	for _, addr := range bps {
+		fmt.Fprintf(d.out, "0x%03X\t%s\n", addr, decode(d.cpu, addr))
+	}
+	for i := range watches {
+		fmt.Fprintf(d.out, "Watchpoint %d: %s\n", watches[i].ID, &watches[i])
+	}
+	return nil
+}
+
+func (d *Debugger) cmdPause(args []string) error {
+	d.cpu.MM.ClearRunStep()
+	d.cpu.MM.Activate()
+	return nil
+}
+
+func (d *Debugger) cmdStep(args []string) error {
+	n, err := parseCount(args, 0, 1)
+	if err != nil {
+		return err
+	}
+	d.cpu.MM.Activate()
+	d.cpu.MM.SetRunSteps(n)
+	return nil
+}
+
+func (d *Debugger) cmdContinue(args []string) error {
+	d.cpu.MM.ClearRunStep()
+	d.cpu.MM.Continue()
+	return nil
+}
+
+func (d *Debugger) cmdRegisters(args []string) error {
+	c := d.cpu
+	for i := range c.V {
+		fmt.Fprintf(d.out, "V%X=%02X", i, c.V[i])
+		if i%8 == 7 {
+			fmt.Fprintln(d.out)
+		} else {
+			fmt.Fprint(d.out, " ")
+		}
+	}
+	fmt.Fprintf(d.out, "I=0x%03X PC=0x%03X SP=%d DT=%02X ST=%02X Cycles=%d\n",
+		c.I, c.Pc, c.Sp, c.DelayTimer, c.SoundTimer, c.Cycles)
+	if w := c.KeyWait; w.Active && w.Pressed {
+		fmt.Fprintf(d.out, "FX0A waiting for key %X to be released, for V%X\n", w.Key, w.Reg)
+	} else if w.Active {
+		fmt.Fprintf(d.out, "FX0A waiting for a key, for V%X\n", w.Reg)
+	}
+	return nil
+}
+
+func (d *Debugger) cmdMem(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errUsage
+	}
+	addr, err := parseAddr(args[0])
+	if err != nil {
+		return err
+	}
+	n, err := parseCount(args, 1, 64)
+	if err != nil {
+		return err
+	}
+	if end := len(d.cpu.Memory) - int(addr); n > end {
+		n = end
+	}
+
+	for i := 0; i < n; i += 16 {
+		fmt.Fprintf(d.out, "0x%04X:", int(addr)+i)
+		for j := i; j < i+16 && j < n; j++ {
+			fmt.Fprintf(d.out, " %02X", d.cpu.Memory[int(addr)+j])
+		}
+		fmt.Fprintln(d.out)
+	}
+	return nil
+}
+
+func (d *Debugger) cmdDisasm(args []string) error {
+	if len(args) > 2 {
+		return errUsage
+	}
+	addr := d.cpu.GetPc()
+	if addr >= 8 {
+		addr -= 8
+	}
+	if len(args) > 0 {
+		var err error
+		if addr, err = parseAddr(args[0]); err != nil {
+			return err
+		}
+	}
+	n, err := parseCount(args, 1, 10)
+	if err != nil {
+		return err
+	}
+	d.disassemble(addr, n)
+	return nil
+}
+
+//disassemble prints n instructions starting at addr, marking the current one
+func (d *Debugger) disassemble(addr uint16, n int) {
+	for i := 0; i < n && int(addr)+1 < len(d.cpu.Memory); i++ {
+		marker := "  "
+		if addr == d.cpu.GetPc() {
+			marker = "=>"
+		}
+		if d.cpu.MM.IsBP(addr) {
+			marker = marker[:1] + "*"
+		}
+		fmt.Fprintf(d.out, "%s 0x%03X  %04X  %s\n", marker, addr, opcodeAt(d.cpu, addr), decode(d.cpu, addr))
+		addr += 2
+	}
+}
+
+//opcodeAt reads the instruction word at addr
+func opcodeAt(c *core.Chip8, addr uint16) uint16 {
+	return uint16(c.Memory[addr])<<8 | uint16(c.Memory[addr+1])
+}
+
+//decode disassembles the instruction at addr
+func decode(c *core.Chip8, addr uint16) string {
+	return strings.TrimLeft(utils.Decode(opcodeAt(c, addr)).EmitStr, "\t")
+}
+
+func (d *Debugger) cmdSet(args []string) error {
+	if len(args) < 2 {
+		return errUsage
+	}
+	c := d.cpu
+	target := strings.ToLower(args[0])
+
+	//Registers are assigned directly, so as not to trigger watchpoints
+	if len(target) == 2 && target[0] == 'v' {
+		reg, err := parseReg(target)
+		if err != nil {
+			return fmt.Errorf("invalid register %q", args[0])
+		}
+		val, err := parseNum(args[1], 8)
+		if err != nil {
+			return fmt.Errorf("invalid value %q", args[1])
+		}
+		c.V[reg] = uint8(val)
+		return nil
+	}
+
+	switch target {
+	case "i", "pc":
+		val, err := parseAddr(args[1])
+		if err != nil {
+			return err
+		}
+		if target == "i" {
+			c.I = val
+		} else {
+			c.Pc = val
+		}
+	case "sp", "dt", "st":
+		val, err := parseNum(args[1], 8)
+		if err != nil {
+			return fmt.Errorf("invalid value %q", args[1])
+		}
+		switch target {
+		case "sp":
+			c.Sp = uint8(val)
+		case "dt":
+			c.DelayTimer = uint8(val)
+		case "st":
+			c.SoundTimer = uint8(val)
+		}
+	default:
+		addr, err := parseAddr(args[0])
+		if err != nil {
+			return fmt.Errorf("unknown register %q", args[0])
+		}
+		for i, arg := range args[1:] {
+			val, err := parseNum(arg, 8)
+			if err != nil {
+				return fmt.Errorf("invalid value %q", arg)
+			}
+			c.Memory[addr+uint16(i)] = uint8(val)
+		}
+	}
+	return nil
+}
+
+func (d *Debugger) cmdBacktrace(args []string) error {
+	c := d.cpu
+	fmt.Fprintf(d.out, "#0  0x%03X\t%s\n", c.Pc, decode(c, c.Pc))
+	//The stack grows down from the top, the most recent return address is just
+	//above the stack pointer, which wraps below 0 once the stack is full.
+	start := int(c.Sp) + 1
+	if c.Sp == 0xFF {
+		start = 0
+	}
+	frame := 1
+	for sp := start; sp < len(c.S); sp++ {
+		ret := c.S[sp]
+		fmt.Fprintf(d.out, "#%d  0x%03X\tcalled from 0x%03X\n", frame, ret, ret-2)
+		frame++
+	}
+	return nil
+}
+
+func (d *Debugger) cmdHelp(args []string) error {
+	for _, cmd := range commandList {
+		name := strings.Join(cmd.names, ", ")
+		fmt.Fprintf(d.out, "%-20s %-22s %s\n", name, cmd.usage, cmd.help)
+	}
+	fmt.Fprintln(d.out, "Numbers are decimal, or hex with a 0x or $ prefix. An empty line repeats the last command.")
+	return nil
+}
@@ -0,0 +1,151 @@
+package debugger
+
+import (
+	"bufio"
+	"chip8emu/core"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+//prompt printed whenever the debugger is ready for the next command
+const prompt = "dbg> "
+
+//Debugger an interactive command line monitor for a machine, built on its
+//MachineMonitor. Commands are read from in on their own goroutine and executed on
+//the machine's goroutine by Service, once per frame.
+type Debugger struct {
+	cpu     *core.Chip8
+	out     io.Writer
+	cmds    chan string
+	lastCmd string
+	//stopped whether the machine was last reported as stopped
+	stopped bool
+}
+
+//NewDebugger constructor to attach a debugger to a machine, reading commands from
+//in and writing its output to out
+func NewDebugger(cpu *core.Chip8, in io.Reader, out io.Writer) *Debugger {
+	d := &Debugger{cpu: cpu, out: out, cmds: make(chan string)}
+	cpu.Debugger = d
+	go d.readCommands(in)
+	fmt.Fprintln(out, "Chip-8 debugger, type help for a list of commands")
+	fmt.Fprint(out, prompt)
+	return d
+}
+
+//readCommands forwards lines read from in to the machine's goroutine
+func (d *Debugger) readCommands(in io.Reader) {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		d.cmds <- scanner.Text()
+	}
+	close(d.cmds)
+}
+
+//Service reports the machine stopping and runs any pending commands. It must be
+//called on the machine's goroutine, see core.DebuggerInterface.
+func (d *Debugger) Service() {
+	if d.reportStop() {
+		fmt.Fprint(d.out, prompt)
+	}
+	for {
+		select {
+		case line, ok := <-d.cmds:
+			if !ok {
+				d.cmds = nil
+				return
+			}
+			if err := d.Exec(line); err != nil {
+				fmt.Fprintf(d.out, "error: %v\n", err)
+			}
+			d.reportStop()
+			fmt.Fprint(d.out, prompt)
+		default:
+			return
+		}
+	}
+}
+
+//reportStop prints the current instruction when the machine has come to a stop
+//on a breakpoint or at the end of a step, returning true if it did
+func (d *Debugger) reportStop() bool {
+	mm := d.cpu.MM
+	stopped := mm.IsActive() && !mm.IsRunStep()
+	report := stopped && !d.stopped
+	d.stopped = stopped
+	if report {
+		pc := d.cpu.GetPc()
+		hits := mm.TakeHits()
+		reason := "Stopped"
+		if len(hits) > 0 {
+			reason = "Watchpoint"
+		} else if mm.IsBP(pc) {
+			reason = "Breakpoint"
+		}
+		fmt.Fprintf(d.out, "\n%s at 0x%03X\n", reason, pc)
+		for i := range hits {
+			fmt.Fprintln(d.out, &hits[i])
+		}
+		d.disassemble(pc, 1)
+	}
+	return report
+}
+
+//Exec runs a single command line, an empty line repeats the previous command
+func (d *Debugger) Exec(line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		line = d.lastCmd
+	}
+	if line == "" {
+		return nil
+	}
+	d.lastCmd = line
+
+	fields := strings.Fields(line)
+	cmd, exists := commands[strings.ToLower(fields[0])]
+	if !exists {
+		return fmt.Errorf("unknown command %q, type help for a list of commands", fields[0])
+	}
+	return cmd.run(d, fields[1:])
+}
+
+//parseNum parses a number given in decimal, or in hex with a 0x or $ prefix
+func parseNum(s string, bits int) (uint64, error) {
+	if strings.HasPrefix(s, "$") {
+		return strconv.ParseUint(s[1:], 16, bits)
+	}
+	return strconv.ParseUint(s, 0, bits)
+}
+
+//parseAddr parses a memory address, see parseNum
+func parseAddr(s string) (uint16, error) {
+	n, err := parseNum(s, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q", s)
+	}
+	return uint16(n), nil
+}
+
+//parseReg parses a V register name, v0 to vf
+func parseReg(s string) (uint8, error) {
+	if len(s) != 2 || (s[0] != 'v' && s[0] != 'V') {
+		return 0, fmt.Errorf("invalid register %q", s)
+	}
+	n, err := strconv.ParseUint(s[1:], 16, 4)
+	return uint8(n), err
+}
+
+//parseCount parses an optional count argument, returning def if absent
+func parseCount(args []string, i, def int) (int, error) {
+	if len(args) <= i {
+		return def, nil
+	}
+	n, err := parseNum(args[i], 16)
+	if err != nil || n == 0 {
+		return 0, fmt.Errorf("invalid count %q", args[i])
+	}
+	return int(n), nil
+}
@@ -0,0 +1,183 @@
+package debugger
+
+import (
+	"bytes"
+	"chip8emu/core"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+//framesDisplay a headless display that shuts down after a number of frames
+type framesDisplay struct {
+	*core.HeadlessDisplay
+	frames int
+}
+
+func (d *framesDisplay) Render() {
+	d.frames--
+	if d.frames == 0 {
+		d.Shutdown()
+	}
+}
+
+//runFrames runs the machine through Start for n frames, servicing the debugger
+func runFrames(chip *core.Chip8, n int) {
+	chip.DisplayHandler = &framesDisplay{core.NewHeadlessDisplay(chip), n}
+	chip.Start()
+}
+
+func newTestDebugger() (*core.Chip8, *Debugger, *bytes.Buffer) {
+	chip := core.NewChip8()
+	core.NewHeadlessDisplay(chip)
+	out := &bytes.Buffer{}
+	d := NewDebugger(chip, strings.NewReader(""), out)
+	return chip, d, out
+}
+
+func TestBreakStepContinue(t *testing.T) {
+	chip, d, out := newTestDebugger()
+	chip.SetMem(0x200, core.LoadVxFromKk|0x001)
+	chip.SetMem(0x202, core.LoadVxAddKk|0x001)
+	chip.SetMem(0x204, core.Jump|0x202)
+
+	d.Exec("break 0x204")
+	runFrames(chip, 2)
+
+	if chip.MM.IsActive() && chip.GetPc() == 0x204 && chip.GetV(0) == 2 {
+		t.Log("Stopped on breakpoint - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected stop at 0x204 with V0 2, actual PC 0x%03X V0 %v", chip.GetPc(), chip.GetV(0))
+		t.Error(msg)
+	}
+
+	d.Exec("step 2")
+	runFrames(chip, 1)
+
+	if chip.MM.IsActive() && chip.GetPc() == 0x204 && chip.GetV(0) == 3 {
+		t.Log("Step executes n instructions - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected stop at 0x204 with V0 3, actual PC 0x%03X V0 %v", chip.GetPc(), chip.GetV(0))
+		t.Error(msg)
+	}
+
+	d.Exec("continue")
+	runFrames(chip, 1)
+
+	if chip.MM.IsActive() && chip.GetPc() == 0x204 && chip.GetV(0) == 4 {
+		t.Log("Continue runs past the breakpoint to the next hit - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected stop at 0x204 with V0 4, actual PC 0x%03X V0 %v", chip.GetPc(), chip.GetV(0))
+		t.Error(msg)
+	}
+
+	if strings.Contains(out.String(), "Breakpoint at 0x204") {
+		t.Log("Breakpoint reported - Test Passed")
+	} else {
+		t.Error("Breakpoint not reported, output: ", out.String())
+	}
+
+	out.Reset()
+	d.Exec("delete 0x204")
+	d.Exec("list")
+	if strings.Contains(out.String(), "No breakpoints") {
+		t.Log("Breakpoint deleted - Test Passed")
+	} else {
+		t.Error("Expected no breakpoints, output: ", out.String())
+	}
+}
+
+func TestSetAndDump(t *testing.T) {
+	chip, d, out := newTestDebugger()
+
+	for _, cmd := range []string{"set va 0x2a", "set i $300", "set dt 5", "set 0x300 1 2 $ff"} {
+		if err := d.Exec(cmd); err != nil {
+			t.Error("Unexpected error for ", cmd, ": ", err)
+		}
+	}
+
+	if chip.GetV(0xA) == 0x2A && chip.GetI() == 0x300 && chip.GetDT() == 5 && chip.Memory[0x302] == 0xFF {
+		t.Log("Set registers and memory - Test Passed")
+	} else {
+		msg := fmt.Sprintf("VA = %v, I = 0x%03X, DT = %v, [0x302] = %v", chip.GetV(0xA), chip.GetI(), chip.GetDT(), chip.Memory[0x302])
+		t.Error(msg)
+	}
+
+	out.Reset()
+	d.Exec("mem 0x300 4")
+	if out.String() == "0x0300: 01 02 FF 00\n" {
+		t.Log("Memory dump - Test Passed")
+	} else {
+		t.Error("Unexpected memory dump: ", out.String())
+	}
+
+	out.Reset()
+	d.Exec("registers")
+	if strings.Contains(out.String(), "VA=2A") && strings.Contains(out.String(), "I=0x300") {
+		t.Log("Registers - Test Passed")
+	} else {
+		t.Error("Unexpected registers: ", out.String())
+	}
+
+	if err := d.Exec("set vg 1"); err != nil {
+		t.Log("Invalid register rejected - Test Passed")
+	} else {
+		t.Error("Expected an error setting vg")
+	}
+
+	if err := d.Exec("frobnicate"); err != nil {
+		t.Log("Unknown command rejected - Test Passed")
+	} else {
+		t.Error("Expected an error for an unknown command")
+	}
+}
+
+func TestBacktrace(t *testing.T) {
+	chip, d, out := newTestDebugger()
+	chip.Push(0x202)
+	chip.Push(0x30A)
+	chip.SetPc(0x400)
+
+	out.Reset()
+	d.Exec("bt")
+	expected := "#1  0x30A\tcalled from 0x308\n#2  0x202\tcalled from 0x200\n"
+	if strings.HasSuffix(out.String(), expected) {
+		t.Log("Backtrace - Test Passed")
+	} else {
+		t.Error("Unexpected backtrace: ", out.String())
+	}
+}
+
+func TestWatch(t *testing.T) {
+	chip, d, out := newTestDebugger()
+	chip.SetMem(0x200, core.LoadVxFromKk|0x005)
+	chip.SetMem(0x202, core.LoadIFromNnn|0x300)
+	chip.SetMem(0x204, core.StoreV0ToVx)
+	chip.SetMem(0x206, core.Jump|0x206)
+
+	if err := d.Exec("watch 0x300-0x30f"); err != nil {
+		t.Fatal("watch failed: ", err)
+	}
+	runFrames(chip, 1)
+	d.Service()
+
+	expected := "Watchpoint 1: write mem 0x300 0x00 -> 0x05 by F055 at 0x204"
+	if chip.MM.IsActive() && chip.GetPc() == 0x206 && strings.Contains(out.String(), expected) {
+		t.Log("Write watchpoint stops after the writing instruction - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected stop at 0x206 reporting %q, actual PC 0x%03X output %q", expected, chip.GetPc(), out.String())
+		t.Error(msg)
+	}
+
+	if err := d.Exec("watch v0-vf rw"); err != nil {
+		t.Error("Unexpected error watching registers: ", err)
+	}
+	if err := d.Exec("watch vf-v0"); err == nil {
+		t.Error("Expected an error for a reversed register range")
+	}
+	if err := d.Exec("unwatch 2"); err == nil && len(chip.MM.Watches()) == 1 {
+		t.Log("Watchpoint deleted - Test Passed")
+	} else {
+		t.Error("Expected watchpoint 2 to be deleted, watches: ", chip.MM.Watches())
+	}
+}
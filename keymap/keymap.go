@@ -0,0 +1,387 @@
+package keymap
+
+import (
+	"chip8emu/opts"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//Action an emulator action bound to a host key
+type Action string
+
+//Actions, as named in keymap files
+const (
+	Pause      Action = "pause"
+	Continue   Action = "continue"
+	Step       Action = "step"
+	Reset      Action = "reset"
+	SaveState  Action = "save-state"
+	LoadState  Action = "load-state"
+	NextSlot   Action = "next-slot"
+	Screenshot Action = "screenshot"
+	Record     Action = "record"
+	SpeedUp    Action = "speed-up"
+	SpeedDown  Action = "speed-down"
+	Turbo      Action = "turbo"
+)
+
+var actions = []Action{Pause, Continue, Step, Reset, SaveState, LoadState, NextSlot, Screenshot, Record, SpeedUp, SpeedDown, Turbo}
+
+//none removes a binding in a keymap file
+const none = "none"
+
+//Keymap the bindings of host keys, by lower case key name as given by SDL, e.g.
+//"q", "f1" or "keypad 7", to Chip-8 keys and emulator actions. A host key is
+//bound to at most one of either. Game controller buttons, stick directions and
+//triggers are bound to Chip-8 keys by control name, see Controls.
+type Keymap struct {
+	Keys    map[string]uint8
+	Actions map[string]Action
+	Buttons map[string]uint8
+	//Deadzone the fraction of an analog axis' travel ignored around its centre
+	Deadzone float64
+}
+
+//presets the Chip-8 keys for each preset layout. The keys of the COSMAC VIP hex
+//keypad are laid out as 123C 456D 789E A0BF.
+var presets = map[string]map[string]uint8{
+	//The block of keys from 1 to V on a QWERTY keyboard
+	"qwerty": {
+		"1": 0x1, "2": 0x2, "3": 0x3, "4": 0xC,
+		"q": 0x4, "w": 0x5, "e": 0x6, "r": 0xD,
+		"a": 0x7, "s": 0x8, "d": 0x9, "f": 0xE,
+		"z": 0xA, "x": 0x0, "c": 0xB, "v": 0xF,
+	},
+	//The same block on an AZERTY keyboard, the top row either shifted or not
+	"azerty": {
+		"1": 0x1, "2": 0x2, "3": 0x3, "4": 0xC,
+		"&": 0x1, "é": 0x2, "\"": 0x3, "'": 0xC,
+		"a": 0x4, "z": 0x5, "e": 0x6, "r": 0xD,
+		"q": 0x7, "s": 0x8, "d": 0x9, "f": 0xE,
+		"w": 0xA, "x": 0x0, "c": 0xB, "v": 0xF,
+	},
+	//The numeric keypad's digits as themselves, so 2, 4, 6 and 8 give the usual
+	//directions, and A-F on the keys around them
+	"numpad": {
+		"keypad 0": 0x0, "keypad 1": 0x1, "keypad 2": 0x2, "keypad 3": 0x3, "keypad 4": 0x4,
+		"keypad 5": 0x5, "keypad 6": 0x6, "keypad 7": 0x7, "keypad 8": 0x8, "keypad 9": 0x9,
+		"keypad /": 0xA, "keypad *": 0xB, "keypad -": 0xC, "keypad +": 0xD, "keypad enter": 0xE, "keypad .": 0xF,
+	},
+}
+
+//DefaultDeadzone the deadzone unless a keymap file changes it, enough to ignore
+//the drift of a worn stick
+const DefaultDeadzone = 0.25
+
+//buttons the SDL game controller button names, axes the axis names, both as
+//given by SDL. Sticks are centred at zero and triggers range from zero up.
+var (
+	buttons = []string{"a", "b", "x", "y", "back", "guide", "start", "leftstick", "rightstick",
+		"leftshoulder", "rightshoulder", "dpup", "dpdown", "dpleft", "dpright"}
+	sticks   = []string{"leftx", "lefty", "rightx", "righty"}
+	triggers = []string{"lefttrigger", "righttrigger"}
+)
+
+//defaultButtons the controller bindings unless a keymap file changes them: the
+//D-pad and left stick on the directions of the hex keypad, 2 4 6 8, and the face
+//buttons on the keys around them
+var defaultButtons = map[string]uint8{
+	"dpup": 0x2, "dpleft": 0x4, "dpright": 0x6, "dpdown": 0x8,
+	"lefty-": 0x2, "leftx-": 0x4, "leftx+": 0x6, "lefty+": 0x8,
+	"a": 0x5, "b": 0x0, "x": 0x7, "y": 0x9,
+}
+
+//defaultActions the actions bound unless a keymap file changes them
+var defaultActions = map[string]Action{
+	"f1":  Pause,
+	"f2":  Continue,
+	"f3":  Step,
+	"f4":  Reset,
+	"f5":  SaveState,
+	"f6":  NextSlot,
+	"f9":  LoadState,
+	"f11": Record,
+	"f12": Screenshot,
+	"=":   SpeedUp,
+	"-":   SpeedDown,
+	"tab": Turbo,
+}
+
+//Presets the names of the preset layouts, sorted
+func Presets() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//Default the QWERTY layout with the default actions
+func Default() *Keymap {
+	k := &Keymap{Keys: map[string]uint8{}, Actions: map[string]Action{}, Buttons: map[string]uint8{}, Deadzone: DefaultDeadzone}
+	k.setPreset("qwerty")
+	for name, a := range defaultActions {
+		k.Actions[name] = a
+	}
+	for control, key := range defaultButtons {
+		k.Buttons[control] = key
+	}
+	return k
+}
+
+//Controls the names of the game controller controls that can be bound: the
+//buttons, each stick axis pushed towards - or +, e.g. "leftx-" for the left stick
+//pushed left and "lefty-" for it pushed up, and the triggers
+func Controls() []string {
+	controls := append([]string{}, buttons...)
+	for _, axis := range sticks {
+		controls = append(controls, axis+"-", axis+"+")
+	}
+	return append(controls, triggers...)
+}
+
+//validControl reports whether control is one of the Controls
+func validControl(control string) bool {
+	for _, valid := range Controls() {
+		if control == valid {
+			return true
+		}
+	}
+	return false
+}
+
+//AxisControl the control an analog axis is pushed to, or "" if it's within the
+//deadzone. value is as reported by SDL, from -32768 to 32767.
+func (k *Keymap) AxisControl(axis string, value int16) string {
+	if math.Abs(float64(value)) <= k.Deadzone*math.MaxInt16 {
+		return ""
+	}
+	axis = strings.ToLower(axis)
+	for _, trigger := range triggers {
+		if axis == trigger {
+			return axis
+		}
+	}
+	if value < 0 {
+		return axis + "-"
+	}
+	return axis + "+"
+}
+
+//setPreset replaces the Chip-8 key bindings with those of a preset layout
+func (k *Keymap) setPreset(name string) error {
+	preset, exists := presets[strings.ToLower(name)]
+	if !exists {
+		return fmt.Errorf("unknown keymap preset %q, expected one of: %s", name, strings.Join(Presets(), ", "))
+	}
+	for host := range k.Keys {
+		delete(k.Keys, host)
+	}
+	for host, key := range preset {
+		k.bindKey(host, key)
+	}
+	return nil
+}
+
+//bindKey binds a host key to a Chip-8 key, replacing any action it had
+func (k *Keymap) bindKey(host string, key uint8) {
+	delete(k.Actions, host)
+	k.Keys[host] = key
+}
+
+//bindAction binds a host key to an action, replacing any Chip-8 key it had
+func (k *Keymap) bindAction(host string, a Action) {
+	delete(k.Keys, host)
+	k.Actions[host] = a
+}
+
+//Key the Chip-8 key bound to a host key
+func (k *Keymap) Key(host string) (uint8, bool) {
+	key, exists := k.Keys[strings.ToLower(host)]
+	return key, exists
+}
+
+//Action the action bound to a host key
+func (k *Keymap) Action(host string) (Action, bool) {
+	a, exists := k.Actions[strings.ToLower(host)]
+	return a, exists
+}
+
+//Button the Chip-8 key bound to a game controller control
+func (k *Keymap) Button(control string) (uint8, bool) {
+	key, exists := k.Buttons[strings.ToLower(control)]
+	return key, exists
+}
+
+//Bindings a keymap file's changes to the bindings, applied in order: first the
+//preset replaces the Chip-8 keys, then the keys, actions and buttons are bound.
+//Binding a host key or control to "none" removes its binding.
+type Bindings struct {
+	Preset string `json:"preset"`
+	//Keys the Chip-8 key for host keys, as a hex digit
+	Keys map[string]string `json:"keys"`
+	//Actions the action for host keys
+	Actions map[string]Action `json:"actions"`
+	//Buttons the Chip-8 key for game controller controls, as a hex digit
+	Buttons map[string]string `json:"buttons"`
+	//Deadzone the stick deadzone, from 0 to 1, unchanged if not given
+	Deadzone *float64 `json:"deadzone"`
+}
+
+//File a keymap file, the bindings for all ROMs followed by the overrides for
+//individual ROMs. ROMs are given by file name, with or without the extension, or
+//by SHA-1.
+type File struct {
+	Bindings
+	ROMs map[string]Bindings `json:"roms"`
+}
+
+//apply makes the changes to the keymap
+func (b *Bindings) apply(k *Keymap) error {
+	if b.Preset != "" {
+		if err := k.setPreset(b.Preset); err != nil {
+			return err
+		}
+	}
+	actionHosts := map[string]bool{}
+	for host := range b.Actions {
+		actionHosts[strings.ToLower(host)] = true
+	}
+	for host, key := range b.Keys {
+		host = strings.ToLower(host)
+		if actionHosts[host] {
+			return fmt.Errorf("key %q bound to both a Chip-8 key and an action", host)
+		}
+		if key == none {
+			delete(k.Keys, host)
+			continue
+		}
+		n, err := parseKey(key)
+		if err != nil {
+			return fmt.Errorf("key %q: %v", host, err)
+		}
+		k.bindKey(host, n)
+	}
+	for host, a := range b.Actions {
+		host = strings.ToLower(host)
+		if a == none {
+			delete(k.Actions, host)
+			continue
+		}
+		if !validAction(a) {
+			return fmt.Errorf("key %q: unknown action %q", host, a)
+		}
+		k.bindAction(host, a)
+	}
+	for control, key := range b.Buttons {
+		control = strings.ToLower(control)
+		if !validControl(control) {
+			return fmt.Errorf("unknown controller button %q, expected one of: %s", control, strings.Join(Controls(), ", "))
+		}
+		if key == none {
+			delete(k.Buttons, control)
+			continue
+		}
+		n, err := parseKey(key)
+		if err != nil {
+			return fmt.Errorf("button %q: %v", control, err)
+		}
+		k.Buttons[control] = n
+	}
+	if b.Deadzone != nil {
+		if *b.Deadzone < 0 || *b.Deadzone >= 1 {
+			return fmt.Errorf("deadzone %v out of range, expected 0 up to 1", *b.Deadzone)
+		}
+		k.Deadzone = *b.Deadzone
+	}
+	return nil
+}
+
+//parseKey parses a Chip-8 key given as a hex digit
+func parseKey(key string) (uint8, error) {
+	n, err := strconv.ParseUint(key, 16, 4)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Chip-8 key %q, expected 0-F", key)
+	}
+	return uint8(n), nil
+}
+
+//validAction reports whether a is one of the actions
+func validAction(a Action) bool {
+	for _, valid := range actions {
+		if a == valid {
+			return true
+		}
+	}
+	return false
+}
+
+//Parse reads a keymap file, applying the bindings for all ROMs and then those for
+//the ROM at romPath, if any, to the default keymap
+func Parse(data []byte, romPath string, rom []byte) (*Keymap, error) {
+	f := &File{}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, err
+	}
+	k := Default()
+	if err := f.apply(k); err != nil {
+		return nil, err
+	}
+
+	//Overrides by file name come before those by SHA-1
+	base := strings.ToLower(filepath.Base(romPath))
+	names := []string{base}
+	if ext := filepath.Ext(base); ext != "" {
+		names = append(names, strings.TrimSuffix(base, ext))
+	}
+	if rom != nil {
+		sum := sha1.Sum(rom)
+		names = append(names, hex.EncodeToString(sum[:]))
+	}
+	for _, name := range names {
+		for match, b := range f.ROMs {
+			if strings.ToLower(match) != name {
+				continue
+			}
+			if err := b.apply(k); err != nil {
+				return nil, fmt.Errorf("ROM %s: %v", match, err)
+			}
+		}
+	}
+	return k, nil
+}
+
+//New the keymap given by the run options' --keymap, either a preset or a keymap
+//file, for the ROM being run
+func New(o *opts.Opts) (*Keymap, error) {
+	k := Default()
+	if o.Keymap == "" {
+		return k, nil
+	}
+	if _, exists := presets[strings.ToLower(o.Keymap)]; exists {
+		return k, k.setPreset(o.Keymap)
+	}
+
+	data, err := ioutil.ReadFile(o.Keymap)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("keymap %q is neither a preset, one of: %s, nor a file", o.Keymap, strings.Join(Presets(), ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+	rom, _ := ioutil.ReadFile(o.File)
+	if k, err = Parse(data, o.File, rom); err != nil {
+		return nil, fmt.Errorf("%s: %v", o.Keymap, err)
+	}
+	return k, nil
+}
@@ -0,0 +1,154 @@
+package keymap
+
+import (
+	"chip8emu/opts"
+	"fmt"
+	"testing"
+)
+
+func TestDefault(t *testing.T) {
+	k := Default()
+	key, isKey := k.Key("W")
+	a, isAction := k.Action("F12")
+	if isKey && key == 0x5 && isAction && a == Screenshot && len(k.Keys) == 16 {
+		t.Log("QWERTY layout with the default actions - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected default keymap %+v", k)
+		t.Error(msg)
+	}
+}
+
+func TestPresets(t *testing.T) {
+	k, err := New(&opts.Opts{Keymap: "AZERTY"})
+	z, _ := k.Key("z")
+	amp, _ := k.Key("&")
+	_, w := k.Key("w")
+	if err == nil && z == 0x5 && amp == 0x1 && w {
+		t.Log("AZERTY preset - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected AZERTY keymap %+v, error %v", k, err)
+		t.Error(msg)
+	}
+
+	k, err = New(&opts.Opts{Keymap: "numpad"})
+	up, _ := k.Key("Keypad 8")
+	_, q := k.Key("q")
+	if err == nil && up == 0x8 && !q {
+		t.Log("Numpad preset replaces the keys - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected numpad keymap %+v, error %v", k, err)
+		t.Error(msg)
+	}
+}
+
+const testFile = `{
+	"preset": "azerty",
+	"keys": {"Up": "5", "Left": "7", "Space": "6"},
+	"actions": {"F12": "none", "P": "screenshot", "Backspace": "reset"},
+	"roms": {
+		"BRIX": {"keys": {"Left": "4", "Right": "6"}},
+		"ufo.ch8": {"preset": "numpad"},
+		"52c47f4e700e5e53dadc79e1d90f4a938f028a88": {"actions": {"Up": "pause"}}
+	}
+}`
+
+func TestParse(t *testing.T) {
+	k, err := Parse([]byte(testFile), "games/TETRIS", nil)
+	z, _ := k.Key("z")
+	up, _ := k.Key("up")
+	_, f12 := k.Action("f12")
+	p, _ := k.Action("p")
+	_, pKey := k.Key("p")
+	if err == nil && z == 0x5 && up == 0x5 && !f12 && p == Screenshot && !pKey {
+		t.Log("Bindings for all ROMs applied - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected keymap %+v, error %v", k, err)
+		t.Error(msg)
+	}
+
+	k, err = Parse([]byte(testFile), "games/BRIX", nil)
+	left, _ := k.Key("left")
+	right, _ := k.Key("right")
+	if err == nil && left == 0x4 && right == 0x6 {
+		t.Log("ROM override by name - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected BRIX keymap %+v, error %v", k, err)
+		t.Error(msg)
+	}
+
+	k, err = Parse([]byte(testFile), "roms/UFO.ch8", nil)
+	_, z2 := k.Key("z")
+	nine, _ := k.Key("keypad 9")
+	if err == nil && !z2 && nine == 0x9 {
+		t.Log("ROM override by name with extension - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected UFO keymap %+v, error %v", k, err)
+		t.Error(msg)
+	}
+
+	//SHA-1 of the bytes "UFO"
+	k, err = Parse([]byte(testFile), "renamed.ch8", []byte("UFO"))
+	a, isAction := k.Action("up")
+	_, isKey := k.Key("up")
+	if err == nil && isAction && a == Pause && !isKey {
+		t.Log("ROM override by SHA-1, an action replacing a key - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected keymap by SHA-1 %+v, error %v", k, err)
+		t.Error(msg)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, file := range []string{
+		`{"preset": "dvorak"}`,
+		`{"keys": {"q": "G"}}`,
+		`{"actions": {"q": "explode"}}`,
+		`{"keys": {"q": "1"}, "actions": {"Q": "reset"}}`,
+		`{"roms": {"BRIX": {"keys": {"q": "10"}}}}`,
+		`{"keys": ["q"]}`,
+		`{"buttons": {"select": "1"}}`,
+		`{"buttons": {"a": "Z"}}`,
+		`{"deadzone": 1.5}`,
+	} {
+		if _, err := Parse([]byte(file), "games/BRIX", nil); err != nil {
+			t.Log("Invalid keymap rejected: ", err, " - Test Passed")
+		} else {
+			t.Error("Expected an error for ", file)
+		}
+	}
+}
+
+func TestButtons(t *testing.T) {
+	k := Default()
+	up, _ := k.Button("DPUP")
+	left, _ := k.Button(k.AxisControl("leftx", -20000))
+	_, centred := k.Button(k.AxisControl("leftx", 5000))
+	if up == 0x2 && left == 0x4 && !centred && k.AxisControl("lefty", 32767) == "lefty+" {
+		t.Log("Default D-pad and left stick bindings - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected default buttons %+v", k.Buttons)
+		t.Error(msg)
+	}
+
+	if k.AxisControl("righttrigger", 30000) == "righttrigger" && k.AxisControl("lefttrigger", 100) == "" &&
+		k.AxisControl("lefty", -32768) == "lefty-" {
+		t.Log("Triggers and full travel - Test Passed")
+	} else {
+		t.Error("Unexpected axis controls")
+	}
+
+	file := `{
+		"buttons": {"a": "6", "dpup": "none"},
+		"roms": {"BRIX": {"buttons": {"dpleft": "4", "righttrigger": "5"}, "deadzone": 0.5}}
+	}`
+	k, err := Parse([]byte(file), "games/BRIX", nil)
+	a, _ := k.Button("a")
+	_, dpup := k.Button("dpup")
+	fire, _ := k.Button("righttrigger")
+	if err == nil && a == 0x6 && !dpup && fire == 0x5 && k.Deadzone == 0.5 && k.AxisControl("leftx", -10000) == "" {
+		t.Log("Buttons and deadzone from a keymap file, per ROM - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected buttons %+v deadzone %v, error %v", k.Buttons, k.Deadzone, err)
+		t.Error(msg)
+	}
+}
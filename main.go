@@ -1,37 +1,217 @@
 package main
 
 import (
+	"chip8emu/backend"
+	"chip8emu/capture"
 	"chip8emu/core"
+	"chip8emu/debugger"
 	"chip8emu/opts"
-	"chip8emu/view"
+	_ "chip8emu/terminal"
+	"chip8emu/trace"
+	_ "chip8emu/view"
+	"crypto/sha1"
 	"fmt"
-	"sync"
+	"io"
+	"os"
+	"strings"
 
 	"github.com/jessevdk/go-flags"
 )
 
-var wg = sync.WaitGroup{}
-
 var chip *core.Chip8
 
+//tracer the execution trace requested by --trace, closed once the command is done
+var tracer *trace.Writer
+
 func main() {
 
-	opts := opts.Opts{}
-	_, err := flags.Parse(&opts)
+	cmds := opts.Commands{}
+	parser := flags.NewParser(&cmds, flags.Default)
+
+	//Without a command, run the ROM as before subcommands were added
+	args := os.Args[1:]
+	if len(args) > 0 && strings.HasPrefix(args[0], "-") && args[0] != "-h" && args[0] != "--help" {
+		args = append([]string{"run"}, args...)
+	}
+
+	if _, err := parser.ParseArgs(args); err != nil {
+		if flagsErr, ok := err.(*flags.Error); ok && flagsErr.Type == flags.ErrHelp {
+			return
+		}
+		os.Exit(1)
+	}
+
+	var err error
+	switch parser.Active.Name {
+	case "run":
+		err = run(&cmds.Run)
+	case "disasm":
+		err = disassemble(&cmds.Disasm)
+	case "asm":
+		err = assemble(&cmds.Asm)
+	case "info":
+		err = info(&cmds.Info)
+	case "trace":
+		err = traceROM(&cmds.Trace)
+	case "test":
+		err = test(&cmds.Test)
+	case "compare":
+		err = compare(&cmds.Compare)
+	case "replay":
+		err = replay(&cmds.Replay)
+	}
+	if tracer != nil {
+		if traceErr := tracer.Close(); err == nil {
+			err = traceErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+//newMachine creates a machine configured with the given quirks and clock speed
+func newMachine(m *opts.MachineOpts) (*core.Chip8, error) {
+	c := core.NewChip8()
 
+	quirks, err := core.QuirksProfile(m.Quirks)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	for _, q := range m.Quirk {
+		if err := quirks.Set(q); err != nil {
+			return nil, err
+		}
 	}
+	c.Quirks = quirks
 
-	chip = core.NewChip8()
+	if m.CPUHz > 0 {
+		c.SetClockHz(m.CPUHz)
+	}
+	if m.IPF > 0 {
+		c.SetCyclesPerFrame(m.IPF)
+	}
+	if m.Seed != 0 {
+		c.SeedRandom(m.Seed)
+	}
 
-	//chip.GfxClipping = core.DrawClippingEnabled
+	if m.Trace != "" {
+		if err := startTrace(c, m); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
 
-	view.NewSDLDisplayRenderer(chip, &wg, &opts)
+//startTrace attaches a tracer to the machine as given by the --trace options
+func startTrace(c *core.Chip8, m *opts.MachineOpts) error {
+	format, err := trace.ParseFormat(m.TraceFormat)
+	if err != nil {
+		return err
+	}
+	var addrs, cycles *trace.Range
+	if m.TraceAddr != "" {
+		if addrs, err = trace.ParseRange(m.TraceAddr); err != nil {
+			return err
+		}
+	}
+	if m.TraceCycles != "" {
+		if cycles, err = trace.ParseRange(m.TraceCycles); err != nil {
+			return err
+		}
+	}
+	if tracer, err = trace.Create(m.Trace, format); err != nil {
+		return err
+	}
+	tracer.Addrs, tracer.Cycles = addrs, cycles
+	c.Tracer = tracer
+	return nil
+}
+
+//run runs a ROM in the emulator, using the front end chosen by --backend
+func run(opts *opts.Opts) error {
+	if opts.File == "" {
+		opts.File = opts.Args.ROM
+	}
+	if opts.File == "" {
+		return fmt.Errorf("no ROM given, use -f or pass it as an argument")
+	}
+
+	var err error
+	if chip, err = newMachine(&opts.MachineOpts); err != nil {
+		return err
+	}
+
+	if opts.Debug || opts.DebugTTY != "" {
+		in, out := io.Reader(os.Stdin), io.Writer(os.Stdout)
+		if opts.DebugTTY != "" {
+			tty, err := os.OpenFile(opts.DebugTTY, os.O_RDWR, 0)
+			if err != nil {
+				return err
+			}
+			defer tty.Close()
+			in, out = tty, tty
+		}
+		debugger.NewDebugger(chip, in, out)
+		chip.MM.Activate()
+	}
+
+	frontEnd, err := backend.New(opts.Backend, chip, opts)
+	if err != nil {
+		return err
+	}
+	var recorder *capture.Recorder
+	if opts.Record != "" {
+		recorder = capture.NewRecorder(chip, opts.Record, capture.NewOptions(opts))
+	}
 	fmt.Printf("FILE: %v\n", opts.File)
-	chip.Load(opts.File)
-	chip.Start()
-	wg.Wait()
+	rom, err := loadROM(chip, opts.File)
+	if err != nil {
+		frontEnd.Shutdown()
+		return err
+	}
+	movie, err := startMovie(opts, rom)
+	if err != nil {
+		frontEnd.Shutdown()
+		return err
+	}
+	if err := chip.Start(); err != nil {
+		fmt.Printf("Machine stopped: %v\n", err)
+	}
+	frontEnd.Shutdown()
+	if recorder != nil {
+		err = recorder.Close()
+	}
+	if movie != nil {
+		if movieErr := writeFile(opts.Movie, movie.Finish().Encode); err == nil {
+			err = movieErr
+		}
+	}
+	return err
+}
+
+//startMovie starts playing back the movie given by --play, and recording the input
+//to that given by --movie, which when both are given continues the movie played
+func startMovie(opts *opts.Opts, rom []byte) (*core.MovieRecorder, error) {
+	if opts.Play != "" {
+		movie, err := readMovie(opts.Play, rom)
+		if err != nil {
+			return nil, err
+		}
+		player := chip.PlayMovie(movie)
+		player.OnEnd = func(err error) {
+			if err != nil {
+				fmt.Printf("Movie ended after %d frames: %v\n", player.Frame(), err)
+			} else {
+				fmt.Printf("Movie ended after %d frames, display matches the recording\n", player.Frame())
+			}
+		}
+	}
+	if opts.Movie == "" {
+		return nil, nil
+	}
+	return chip.RecordMovie(sha1.Sum(rom)), nil
 }
 
 func GetChip() *core.Chip8 {
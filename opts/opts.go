@@ -1,10 +1,133 @@
 package opts
 
+//Commands the chip8 tool's subcommands, each with their own options. Run is the
+//default when the first argument is an option rather than a command.
+type Commands struct {
+	Run     Opts        `command:"run" description:"Run a ROM in the emulator"`
+	Disasm  DisasmOpts  `command:"disasm" description:"Disassemble a ROM"`
+	Asm     AsmOpts     `command:"asm" description:"Assemble a source file to a ROM"`
+	Info    InfoOpts    `command:"info" description:"Show the size, extension and hash of a ROM"`
+	Trace   TraceOpts   `command:"trace" description:"Run a ROM headless, tracing each instruction executed to stdout or --trace"`
+	Test    TestOpts    `command:"test" description:"Run a ROM headless and print or check the final screen"`
+	Compare CompareOpts `command:"compare" description:"Run a ROM in lock-step with a reference trace, stopping at the first divergence"`
+	Replay  ReplayOpts  `command:"replay" description:"Play back a movie headless and check it reproduces the recorded display"`
+}
+
+//MachineOpts options configuring the emulated machine, shared by the commands
+//that run a ROM
+type MachineOpts struct {
+	//Interpreter quirks, see core.Quirks
+	Quirks string   `long:"quirks" default:"modern" description:"Quirks profile: vip, chip48, schip, xochip or modern"`
+	Quirk  []string `long:"quirk" description:"Override a single quirk, e.g. --quirk shift-vy=on"`
+	//Clock speed, either in instructions per second or per 60Hz frame
+	CPUHz int `long:"cpu-hz" description:"Emulated clock speed in instructions per second"`
+	IPF   int `long:"ipf" description:"Emulated clock speed in instructions per frame, overrides --cpu-hz"`
+	//Random number generator seed, see core.Chip8.SeedRandom
+	Seed int64 `long:"seed" description:"Seed for the random numbers of CXNN, random if not given"`
+	//Execution trace, see trace.Writer
+	Trace       string `long:"trace" description:"Write a record of each instruction executed to the given file, - for stdout"`
+	TraceFormat string `long:"trace-format" default:"text" description:"Trace format: text or jsonl"`
+	TraceAddr   string `long:"trace-addr" description:"Only trace instructions within an address range, e.g. 0x200-0x2FF"`
+	TraceCycles string `long:"trace-cycles" description:"Only trace instructions within a cycle range, e.g. 1000-2000, either end may be left out"`
+}
+
+//RomArg the ROM file given as a positional argument
+type RomArg struct {
+	ROM string `positional-arg-name:"rom" required:"yes"`
+}
+
+//Opts options for the run command
 type Opts struct {
-	File string `short:"f" long:"file" description:"Game file to load" required:"true"`
+	MachineOpts
+	File string `short:"f" long:"file" description:"Game file to load, may also be given as an argument"`
+	Args struct {
+		ROM string `positional-arg-name:"rom"`
+	} `positional-args:"yes"`
+	//Front end, see backend.Register
+	Backend string `long:"backend" default:"sdl" description:"Front end for display, input and audio: sdl, terminal or null"`
 	//Should change this to a string so as to handle hex values
 	//Blue 23455
 	BgColour uint32 `short:"b" long:"bg-colour" description:"Background Colour" required:"false"`
-	XSize    int    `long:"x-size" description:"Effective pixel size in pixels"`
-	YSize    int    `long:"y-size" description:"Effective pixel size in pixels"`
+	XSize    int    `long:"x-size" description:"Effective pixel size in pixels, halved for high resolution, odd sizes alternate rounding down and up"`
+	YSize    int    `long:"y-size" description:"Effective pixel size in pixels, halved for high resolution, odd sizes alternate rounding down and up"`
+	//Key bindings, see keymap.Keymap
+	Keymap string `long:"keymap" description:"Key bindings, either a preset layout (qwerty, azerty or numpad) or a keymap file"`
+	//Save state slots
+	StateDir string `long:"state-dir" description:"Directory for save state slots, defaults to the ROM directory"`
+	//Interactive debugger, on stdin or a separate terminal
+	Debug    bool   `long:"debug" description:"Start stopped, with the debugger reading commands from stdin"`
+	DebugTTY string `long:"debug-tty" description:"Start stopped, with the debugger on the given terminal, e.g. /dev/pts/3"`
+	//Sound of the beep, see audio.Tone
+	Waveform string  `long:"waveform" default:"square" description:"Beep waveform: square or sine"`
+	ToneHz   float64 `long:"tone-hz" default:"440" description:"Beep frequency in Hz"`
+	Volume   int     `long:"volume" default:"25" description:"Beep volume from 0 to 100"`
+	//Recording of the display, see capture.Recorder
+	Record string `long:"record" description:"Record the display to an animated GIF until the emulator exits"`
+	//Input recording and playback, see core.MovieRecorder and core.MoviePlayer
+	Movie string `long:"movie" description:"Record the input to a movie file until the emulator exits"`
+	Play  string `long:"play" description:"Play back a movie file, then carry on with live input"`
+}
+
+//DisasmOpts options for the disasm command, see utils.WriteDisassembly
+type DisasmOpts struct {
+	Output string `short:"o" long:"output" description:"Source file to write, defaults to stdout"`
+	Linear bool   `long:"linear" description:"List every word as an instruction with its address, without tracing code from data"`
+	Args   RomArg `positional-args:"yes"`
+}
+
+//AsmOpts options for the asm command, see utils.Assemble
+type AsmOpts struct {
+	Output  string `short:"o" long:"output" description:"ROM file to write, defaults to the source file with a .ch8 extension"`
+	Listing string `long:"listing" description:"Listing file to write, giving the address and bytes of each source line"`
+	Symbols string `long:"symbols" description:"Symbol table file to write"`
+	Args    struct {
+		Source string `positional-arg-name:"source" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+//InfoOpts options for the info command
+type InfoOpts struct {
+	Args RomArg `positional-args:"yes"`
+}
+
+//TraceOpts options for the trace command
+type TraceOpts struct {
+	MachineOpts
+	Cycles int    `short:"n" long:"cycles" default:"1000" description:"Number of instructions to execute"`
+	Args   RomArg `positional-args:"yes"`
+}
+
+//TestOpts options for the test command, intended for test ROMs that draw their
+//results to the screen
+type TestOpts struct {
+	MachineOpts
+	Frames int    `long:"frames" default:"120" description:"Number of 60Hz frames to run for"`
+	Expect string `long:"expect" description:"File with the expected screen, as printed by test, exits with an error on a mismatch"`
+	//Captures of the display, see the capture package
+	Screenshot string `long:"screenshot" description:"Save the final screen as a PNG"`
+	Record     string `long:"record" description:"Record every frame to an animated GIF"`
+	WAV        string `long:"wav" description:"Record the beep to a WAV file, 44.1kHz 16 bit mono"`
+	Args       RomArg `positional-args:"yes"`
+}
+
+//CompareOpts options for the compare command, see trace.Comparer
+type CompareOpts struct {
+	MachineOpts
+	Context int  `short:"n" long:"context" default:"10" description:"Number of instructions to show before a divergence"`
+	Timers  bool `long:"timers" description:"Also compare the delay and sound timers"`
+	Args    struct {
+		ROM       string `positional-arg-name:"rom" required:"yes"`
+		Reference string `positional-arg-name:"reference" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+//ReplayOpts options for the replay command, see core.MoviePlayer. The quirks,
+//speed and random seed come from the movie.
+type ReplayOpts struct {
+	Screenshot string `long:"screenshot" description:"Save the final screen as a PNG"`
+	WAV        string `long:"wav" description:"Record the beep to a WAV file, 44.1kHz 16 bit mono"`
+	Args       struct {
+		ROM   string `positional-arg-name:"rom" required:"yes"`
+		Movie string `positional-arg-name:"movie" required:"yes"`
+	} `positional-args:"yes"`
 }
@@ -0,0 +1,359 @@
+package terminal
+
+import (
+	"bufio"
+	"chip8emu/backend"
+	"chip8emu/core"
+	"chip8emu/keymap"
+	"chip8emu/opts"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+//Terminals only report presses, so a held key is seen as a press followed by
+//auto-repeats and is released once they stop. keyPressFrames the number of frames
+//a key stays pressed after its first press, longer than the usual auto-repeat
+//delay of 500-660ms so a held key isn't released before the repeats arrive.
+//keyRepeatFrames the number of frames it stays pressed after each repeat.
+const (
+	keyPressFrames  = 45
+	keyRepeatFrames = 6
+)
+
+//ANSI 256 colour palette indexes for the XO-CHIP plane combinations, matching the
+//SDL palette
+var palette = [4]int{16, 231, 250, 240}
+
+//Keys that always quit, rather than being bound by the keymap
+const (
+	keyCtrlC = 0x03
+	keyEsc   = 0x1B
+)
+
+//keyNames the keymap names of the keys without a single character name
+var keyNames = map[string]byte{"tab": '\t', "space": ' ', "return": '\r', "backspace": 0x7F}
+
+//keyBytes the byte sent by the terminal for a key named in a keymap, only keys
+//sending a single byte can be bound
+func keyBytes(name string) (byte, bool) {
+	if len(name) == 1 && name[0] > ' ' && name[0] < 0x7F {
+		return name[0], true
+	}
+	b, exists := keyNames[name]
+	return b, exists
+}
+
+//cell a character cell, showing two vertically stacked pixels
+type cell struct {
+	top, bottom uint8
+}
+
+//Terminal a backend drawing the display in the terminal with Unicode half block
+//characters, two pixel rows to each line, and reading keys from stdin. Only cells
+//that have changed since the last frame are redrawn.
+type Terminal struct {
+	cpu   *core.Chip8
+	out   *bufio.Writer
+	keys  chan byte
+	alive bool
+
+	//chipKeys and actions bound to the bytes the terminal sends
+	chipKeys map[byte]uint8
+	actions  map[byte]keymap.Action
+
+	//cells the screen as last drawn, nil to redraw everything
+	cells  [][]cell
+	status string
+
+	//held the number of frames each key remains pressed for
+	held [16]int
+
+	frames    int
+	fps       int
+	fpsPeriod time.Time
+
+	//sttyState the terminal settings to restore on shutdown
+	sttyState string
+}
+
+func init() {
+	backend.Register("terminal", func(cpu *core.Chip8, opts *opts.Opts) (backend.Backend, error) {
+		k, err := keymap.New(opts)
+		if err != nil {
+			return nil, err
+		}
+		return New(cpu, k)
+	})
+}
+
+//New puts the terminal on stdin into raw mode and attaches it to the machine as
+//its display, input and audio. Only the keys of the keymap that send a single
+//character can be used.
+func New(cpu *core.Chip8, k *keymap.Keymap) (*Terminal, error) {
+	state, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("terminal backend needs stdin to be a terminal: %v", err)
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+
+	t := newTerminal(cpu, os.Stdout, k)
+	t.sttyState = strings.TrimSpace(state)
+	go t.readKeys(os.Stdin)
+
+	//Hide the cursor and clear the screen
+	t.out.WriteString("\x1b[?25l\x1b[2J")
+	t.out.Flush()
+	return t, nil
+}
+
+//newTerminal attaches a terminal writing to out to the machine, keys are read by
+//the caller
+func newTerminal(cpu *core.Chip8, out io.Writer, k *keymap.Keymap) *Terminal {
+	t := &Terminal{
+		cpu:       cpu,
+		chipKeys:  map[byte]uint8{},
+		actions:   map[byte]keymap.Action{},
+		out:       bufio.NewWriter(out),
+		keys:      make(chan byte, 64),
+		alive:     true,
+		fpsPeriod: time.Now(),
+	}
+	for name, key := range k.Keys {
+		if b, ok := keyBytes(name); ok {
+			t.chipKeys[b] = key
+		}
+	}
+	for name, a := range k.Actions {
+		if b, ok := keyBytes(name); ok {
+			t.actions[b] = a
+		}
+	}
+	cpu.DisplayHandler = t
+	cpu.InputHandler = t
+	cpu.AudioHandler = &bell{out: out}
+	return t
+}
+
+//stty runs stty on stdin with the given arguments
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+//readKeys sends each key read from r to the keys channel. Escape sequences, e.g.
+//from the cursor keys, are dropped rather than being taken as a lone Esc.
+func (t *Terminal) readKeys(r io.Reader) {
+	buf := make([]byte, 64)
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			close(t.keys)
+			return
+		}
+		for i := 0; i < n; i++ {
+			if skip := escapeLen(buf[i:n]); skip > 0 {
+				i += skip - 1
+				continue
+			}
+			t.keys <- buf[i]
+		}
+	}
+}
+
+//escapeLen the length of the escape sequence at the start of b, 0 if there isn't
+//one. A CSI sequence, Esc [, runs to its final byte in the range @ to ~, an SS3
+//sequence, Esc O, is followed by a single byte.
+func escapeLen(b []byte) int {
+	if len(b) < 2 || b[0] != keyEsc {
+		return 0
+	}
+	switch b[1] {
+	case 'O':
+		if len(b) < 3 {
+			return len(b)
+		}
+		return 3
+	case '[':
+		for i := 2; i < len(b); i++ {
+			if b[i] >= '@' && b[i] <= '~' {
+				return i + 1
+			}
+		}
+		return len(b)
+	}
+	return 0
+}
+
+//Render redraws the cells that have changed and the status line
+func (t *Terminal) Render() {
+	width, height := t.cpu.ScreenWidth(), t.cpu.ScreenHeight()
+	rows := height / 2
+	if len(t.cells) != rows || len(t.cells[0]) != width {
+		//First frame or the resolution changed
+		t.out.WriteString("\x1b[0m\x1b[2J")
+		t.cells = nil
+		t.status = ""
+	}
+	redraw := t.cells == nil
+	if redraw {
+		t.cells = make([][]cell, rows)
+		for row := range t.cells {
+			t.cells[row] = make([]cell, width)
+		}
+	}
+
+	for row := 0; row < rows; row++ {
+		cursor := -1
+		for x := 0; x < width; x++ {
+			c := cell{t.cpu.VMem[row*2][x] & core.AllPlanes, t.cpu.VMem[row*2+1][x] & core.AllPlanes}
+			if !redraw && c == t.cells[row][x] {
+				continue
+			}
+			t.cells[row][x] = c
+			if cursor != x {
+				fmt.Fprintf(t.out, "\x1b[%d;%dH", row+1, x+1)
+			}
+			//Upper half block in the top pixel's colour, on the bottom pixel's
+			fmt.Fprintf(t.out, "\x1b[38;5;%dm\x1b[48;5;%dm▀", palette[c.top], palette[c.bottom])
+			cursor = x + 1
+		}
+	}
+
+	t.frames++
+	if now := time.Now(); now.Sub(t.fpsPeriod) >= time.Second {
+		t.fps = int(float64(t.frames)/now.Sub(t.fpsPeriod).Seconds() + 0.5)
+		t.frames, t.fpsPeriod = 0, now
+	}
+	if status := t.statusLine(); status != t.status {
+		fmt.Fprintf(t.out, "\x1b[0m\x1b[%d;1H\x1b[2K%s", rows+1, status)
+		t.status = status
+	}
+	t.out.Flush()
+}
+
+//statusLine the machine state shown below the display
+func (t *Terminal) statusLine() string {
+	state := ""
+	if t.cpu.MM.IsActive() {
+		state = "  stopped"
+	} else if t.cpu.Turbo {
+		state = "  turbo"
+	}
+	return fmt.Sprintf("PC 0x%03X  %3d fps  %dHz%s  Esc to quit", t.cpu.GetPc(), t.fps, t.cpu.ClockHz(), state)
+}
+
+//IsAlive reports whether the terminal is still running, until Esc or Ctrl-C
+func (t *Terminal) IsAlive() bool {
+	return t.alive
+}
+
+//HandleInput presses the keys read since the last frame and releases those no
+//longer being sent
+func (t *Terminal) HandleInput() {
+	for key := range t.held {
+		if t.held[key] > 0 {
+			t.held[key]--
+			if t.held[key] == 0 {
+				t.cpu.ClrKey(uint8(key))
+			}
+		}
+	}
+	for {
+		select {
+		case k, ok := <-t.keys:
+			if !ok {
+				t.alive = false
+				return
+			}
+			t.handleKey(k)
+		default:
+			return
+		}
+	}
+}
+
+//handleKey presses the Chip-8 key for k, or carries out its action. The actions
+//needing files, i.e. save states and captures, aren't available in the terminal.
+func (t *Terminal) handleKey(k byte) {
+	if k >= 'A' && k <= 'Z' {
+		k += 'a' - 'A'
+	}
+	if k == keyEsc || k == keyCtrlC {
+		t.alive = false
+		return
+	}
+	if chipKey, exists := t.chipKeys[k]; exists {
+		//Pressed again while still held, so auto-repeating
+		if t.held[chipKey] > 0 {
+			t.held[chipKey] = keyRepeatFrames
+		} else {
+			t.held[chipKey] = keyPressFrames
+		}
+		t.cpu.SetKey(chipKey)
+		return
+	}
+
+	switch t.actions[k] {
+	case keymap.Pause:
+		t.cpu.MM.Activate()
+	case keymap.Continue:
+		t.cpu.MM.Continue()
+	case keymap.Step:
+		t.cpu.MM.Activate()
+		t.cpu.MM.SetRunStep()
+	case keymap.Reset:
+		t.cpu.Reset()
+	case keymap.SpeedUp:
+		t.cpu.SetCyclesPerFrame(t.cpu.CyclesPerFrame + speedStep(t.cpu.CyclesPerFrame))
+	case keymap.SpeedDown:
+		t.cpu.SetCyclesPerFrame(t.cpu.CyclesPerFrame - speedStep(t.cpu.CyclesPerFrame))
+	case keymap.Turbo:
+		t.cpu.Turbo = !t.cpu.Turbo
+	}
+}
+
+//Shutdown restores the terminal
+func (t *Terminal) Shutdown() {
+	t.alive = false
+	fmt.Fprintf(t.out, "\x1b[0m\x1b[%d;1H\x1b[?25h\r\n", len(t.cells)+2)
+	t.out.Flush()
+	if t.sttyState != "" {
+		stty(t.sttyState)
+	}
+}
+
+//bell sounds the terminal bell at the start of each beep
+type bell struct {
+	out     io.Writer
+	playing bool
+}
+
+//Play rings the bell, unless already playing
+func (b *bell) Play() {
+	if !b.playing {
+		b.out.Write([]byte{'\a'})
+		b.playing = true
+	}
+}
+
+//Stop ends the beep
+func (b *bell) Stop() {
+	b.playing = false
+}
+
+//speedStep the change in instructions per frame for the speed keys, a quarter of
+//the current speed as with the SDL hotkeys
+func speedStep(cyclesPerFrame int) int {
+	if step := cyclesPerFrame / 4; step > 1 {
+		return step
+	}
+	return 1
+}
@@ -0,0 +1,149 @@
+package terminal
+
+import (
+	"bytes"
+	"chip8emu/core"
+	"chip8emu/keymap"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRenderChangedCells(t *testing.T) {
+	chip := core.NewChip8()
+	out := &bytes.Buffer{}
+	term := newTerminal(chip, out, keymap.Default())
+
+	chip.VMem[0][0] = 1
+	chip.VMem[1][1] = 1
+	term.Render()
+	if strings.Count(out.String(), "▀") == 64*16 && strings.Contains(out.String(), "PC 0x200") {
+		t.Log("First frame draws every cell - Test Passed")
+	} else {
+		t.Error("Expected every cell to be drawn, output: ", out.String())
+	}
+
+	out.Reset()
+	term.Render()
+	if !strings.Contains(out.String(), "▀") {
+		t.Log("Unchanged frame draws nothing - Test Passed")
+	} else {
+		t.Error("Expected no cells to be drawn, output: ", out.String())
+	}
+
+	out.Reset()
+	chip.VMem[3][10] = 1
+	term.Render()
+	expected := "\x1b[2;11H\x1b[38;5;16m\x1b[48;5;231m▀"
+	if out.String() == expected {
+		t.Log("Changed cell redrawn - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected %q, actual %q", expected, out.String())
+		t.Error(msg)
+	}
+}
+
+func TestKeyRelease(t *testing.T) {
+	chip := core.NewChip8()
+	term := newTerminal(chip, &bytes.Buffer{}, keymap.Default())
+
+	term.keys <- 'W'
+	term.HandleInput()
+	if chip.GetKey(core.ChipKey5) == 0 {
+		t.Error("Expected W to press key 5")
+	}
+	for i := 0; i < keyPressFrames; i++ {
+		term.HandleInput()
+	}
+	if chip.GetKey(core.ChipKey5) == 0 && term.IsAlive() {
+		t.Log("Key released once no longer sent - Test Passed")
+	} else {
+		t.Error("Expected key 5 to be released")
+	}
+
+	term.keys <- keyEsc
+	term.HandleInput()
+	if !term.IsAlive() {
+		t.Log("Esc quits - Test Passed")
+	} else {
+		t.Error("Expected Esc to quit")
+	}
+}
+
+func TestKeyHeld(t *testing.T) {
+	chip := core.NewChip8()
+	term := newTerminal(chip, &bytes.Buffer{}, keymap.Default())
+
+	//The first auto-repeat arrives after 660ms, 40 frames
+	term.keys <- 'w'
+	for i := 0; i < 40; i++ {
+		term.HandleInput()
+	}
+	term.keys <- 'w'
+	term.HandleInput()
+	if chip.GetKey(core.ChipKey5) != 0 {
+		t.Log("Key held until auto-repeat starts - Test Passed")
+	} else {
+		t.Error("Expected key 5 to be held through the auto-repeat delay")
+	}
+
+	//Repeats every 2 frames
+	for i := 0; i < 30; i++ {
+		if i%2 == 0 {
+			term.keys <- 'w'
+		}
+		term.HandleInput()
+		if chip.GetKey(core.ChipKey5) == 0 {
+			t.Fatal("Expected key 5 to be held while repeating, released at frame ", i)
+		}
+	}
+	for i := 0; i < keyRepeatFrames; i++ {
+		term.HandleInput()
+	}
+	if chip.GetKey(core.ChipKey5) == 0 {
+		t.Log("Key released soon after repeats stop - Test Passed")
+	} else {
+		t.Error("Expected key 5 to be released once the repeats stop")
+	}
+}
+
+func TestReadKeysEscapeSequences(t *testing.T) {
+	chip := core.NewChip8()
+	term := newTerminal(chip, &bytes.Buffer{}, keymap.Default())
+
+	//Cursor up, F1 and Ctrl-Right between keys in a single read
+	go term.readKeys(strings.NewReader("a\x1b[Aw\x1bOPs\x1b[1;5Cd"))
+	read := ""
+	for k := range term.keys {
+		read += string(k)
+	}
+	if read == "awsd" {
+		t.Log("Escape sequences skipped - Test Passed")
+	} else {
+		t.Errorf("Expected keys %q, actual %q", "awsd", read)
+	}
+}
+
+func TestKeymap(t *testing.T) {
+	chip := core.NewChip8()
+	k, _ := keymap.Parse([]byte(`{"preset": "azerty", "actions": {"space": "turbo", "0": "reset"}}`), "", nil)
+	term := newTerminal(chip, &bytes.Buffer{}, k)
+
+	term.keys <- 'z'
+	term.keys <- ' '
+	term.HandleInput()
+	if chip.GetKey(core.ChipKey5) != 0 && chip.Turbo {
+		t.Log("Keys and actions from the keymap - Test Passed")
+	} else {
+		t.Error("Expected Z to press key 5 and space to turn on turbo")
+	}
+
+	chip.SetPc(0x300)
+	term.keys <- '0'
+	term.HandleInput()
+	if chip.GetPc() == 0x200 {
+		t.Log("Reset action - Test Passed")
+	} else {
+		t.Error("Expected a reset, actual PC ", chip.GetPc())
+	}
+}
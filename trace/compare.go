@@ -0,0 +1,217 @@
+package trace
+
+import (
+	"bufio"
+	"chip8emu/core"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+//Reader reads records from a trace in either format, the format is detected
+//from each line so traces converted from other interpreters may use either
+type Reader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+//NewReader constructor for a reader of the trace in r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{s: bufio.NewScanner(r)}
+}
+
+//Read reads the next record, returning io.EOF at the end of the trace. Blank
+//lines and lines starting with # are skipped.
+func (r *Reader) Read() (*Record, error) {
+	for r.s.Scan() {
+		r.line++
+		line := strings.TrimSpace(r.s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var rec *Record
+		var err error
+		if strings.HasPrefix(line, "{") {
+			rec = &Record{}
+			err = json.Unmarshal([]byte(line), rec)
+		} else {
+			rec, err = parseText(line)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", r.line, err)
+		}
+		return rec, nil
+	}
+	if err := r.s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+//parseText parses a record in the text format written by Record.String
+func parseText(line string) (*Record, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 23 || !strings.HasPrefix(fields[3], "V=") {
+		return nil, fmt.Errorf("invalid trace record %q", line)
+	}
+	fields[3] = strings.TrimPrefix(fields[3], "V=")
+
+	r := &Record{}
+	var err error
+	num := func(s string, base, bits int) uint64 {
+		if err != nil {
+			return 0
+		}
+		var n uint64
+		if n, err = strconv.ParseUint(s, base, bits); err != nil {
+			err = fmt.Errorf("invalid trace record %q: %v", line, err)
+		}
+		return n
+	}
+	field := func(s, name string) string {
+		if !strings.HasPrefix(s, name+"=") && err == nil {
+			err = fmt.Errorf("invalid trace record %q: expected %s", line, name)
+		}
+		return strings.TrimPrefix(s, name+"=")
+	}
+
+	r.Cycle = num(fields[0], 10, 64)
+	r.Pc = uint16(num(fields[1], 0, 16))
+	r.Opcode = uint16(num(fields[2], 16, 16))
+	for i := range r.V {
+		r.V[i] = uint8(num(fields[3+i], 16, 8))
+	}
+	r.I = uint16(num(field(fields[19], "I"), 0, 16))
+	r.Sp = uint8(num(field(fields[20], "SP"), 16, 8))
+	r.DT = uint8(num(field(fields[21], "DT"), 16, 8))
+	r.ST = uint8(num(field(fields[22], "ST"), 16, 8))
+	r.Asm = strings.Join(fields[23:], " ")
+	return r, err
+}
+
+//Divergence the first instruction at which the machine differed from a reference
+//trace
+type Divergence struct {
+	Expected *Record
+	Actual   *Record
+	//Diffs the names of the values that differ, PC, opcode, V0-VF, I, SP and, if
+	//compared, DT and ST
+	Diffs []string
+	//History the instructions executed before the divergence, oldest first
+	History []*Record
+}
+
+//Comparer runs a machine in lock-step with a reference trace
+type Comparer struct {
+	//Context the number of preceding instructions kept for a divergence
+	Context int
+	//Timers compares the delay and sound timers, which are often paced
+	//differently between interpreters
+	Timers bool
+	//Steps the number of instructions matched so far
+	Steps uint64
+}
+
+//Compare steps the machine once per reference record, checking the state before
+//each instruction matches the record. Returns the first divergence, or nil if
+//the whole trace matched. The reference must be an unfiltered trace starting
+//from the machine's current state.
+func (cmp *Comparer) Compare(c *core.Chip8, ref *Reader) (*Divergence, error) {
+	var history []*Record
+	for {
+		expected, err := ref.Read()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		pc := c.GetPc()
+		actual := NewRecord(c, pc, uint16(c.Memory[pc])<<8|uint16(c.Memory[pc+1]))
+		if diffs := cmp.diff(expected, actual); len(diffs) > 0 {
+			return &Divergence{Expected: expected, Actual: actual, Diffs: diffs, History: history}, nil
+		}
+
+		if cmp.Context > 0 {
+			if len(history) == cmp.Context {
+				history = history[1:]
+			}
+			history = append(history, actual)
+		}
+		if err := c.Step(); err != nil {
+			return nil, err
+		}
+		cmp.Steps++
+	}
+}
+
+//diff the names of the values differing between the records
+func (cmp *Comparer) diff(expected, actual *Record) []string {
+	var diffs []string
+	if expected.Pc != actual.Pc {
+		diffs = append(diffs, "PC")
+	}
+	if expected.Opcode != actual.Opcode {
+		diffs = append(diffs, "opcode")
+	}
+	for i := range expected.V {
+		if expected.V[i] != actual.V[i] {
+			diffs = append(diffs, fmt.Sprintf("V%X", i))
+		}
+	}
+	if expected.I != actual.I {
+		diffs = append(diffs, "I")
+	}
+	if expected.Sp != actual.Sp {
+		diffs = append(diffs, "SP")
+	}
+	if cmp.Timers && expected.DT != actual.DT {
+		diffs = append(diffs, "DT")
+	}
+	if cmp.Timers && expected.ST != actual.ST {
+		diffs = append(diffs, "ST")
+	}
+	return diffs
+}
+
+//value the named value from a record, as listed in Divergence.Diffs
+func (r *Record) value(name string) string {
+	switch name {
+	case "PC":
+		return fmt.Sprintf("0x%03X", r.Pc)
+	case "opcode":
+		return fmt.Sprintf("%04X", r.Opcode)
+	case "I":
+		return fmt.Sprintf("0x%03X", r.I)
+	case "SP":
+		return fmt.Sprintf("0x%02X", r.Sp)
+	case "DT":
+		return fmt.Sprintf("0x%02X", r.DT)
+	case "ST":
+		return fmt.Sprintf("0x%02X", r.ST)
+	}
+	reg, _ := strconv.ParseUint(name[1:], 16, 8)
+	return fmt.Sprintf("0x%02X", r.V[reg])
+}
+
+//Write reports the divergence, giving the differing values, the instruction being
+//executed and those preceding it. The state differs before the instruction runs,
+//so the cause is usually the last instruction of the history.
+func (d *Divergence) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "Diverged at cycle %d, 0x%03X %04X %s\n", d.Actual.Cycle, d.Actual.Pc, d.Actual.Opcode, d.Actual.Asm)
+	fmt.Fprintf(bw, "\t%-8s%-10s%s\n", "", "expected", "actual")
+	for _, name := range d.Diffs {
+		fmt.Fprintf(bw, "\t%-8s%-10s%s\n", name, d.Expected.value(name), d.Actual.value(name))
+	}
+	if len(d.History) > 0 {
+		fmt.Fprintln(bw, "Preceding instructions:")
+		for _, r := range d.History {
+			fmt.Fprintln(bw, r)
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,177 @@
+package trace
+
+import (
+	"bufio"
+	"chip8emu/core"
+	"chip8emu/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+//Format the format a trace is written in
+type Format int
+
+//Trace formats. Text is one fixed width line per instruction, JSONL one JSON
+//object per line.
+const (
+	FormatText Format = iota
+	FormatJSONL
+)
+
+//ParseFormat parses a format name, text or jsonl
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "text", "":
+		return FormatText, nil
+	case "jsonl", "json":
+		return FormatJSONL, nil
+	}
+	return FormatText, fmt.Errorf("unknown trace format %q, expected text or jsonl", name)
+}
+
+//Record the machine state as an instruction is about to be executed
+type Record struct {
+	//Cycle the number of instructions executed before this one
+	Cycle  uint64    `json:"cycle"`
+	Pc     uint16    `json:"pc"`
+	Opcode uint16    `json:"opcode"`
+	Asm    string    `json:"asm"`
+	V      [16]uint8 `json:"v"`
+	I      uint16    `json:"i"`
+	Sp     uint8     `json:"sp"`
+	DT     uint8     `json:"dt"`
+	ST     uint8     `json:"st"`
+}
+
+//NewRecord captures the state of the machine for the instruction at pc
+func NewRecord(c *core.Chip8, pc, opcode uint16) *Record {
+	return &Record{
+		Cycle:  c.Cycles,
+		Pc:     pc,
+		Opcode: opcode,
+		Asm:    strings.Join(strings.Fields(utils.Decode(opcode).EmitStr), " "),
+		V:      c.V,
+		I:      c.I,
+		Sp:     c.Sp,
+		DT:     c.DelayTimer,
+		ST:     c.SoundTimer,
+	}
+}
+
+//String the record in the text format, e.g.
+//	0000000012 0x20A 6E05 V=00 01 .. 0F I=0x2EA SP=0F DT=00 ST=00 MOVE VE, $5
+func (r *Record) String() string {
+	return fmt.Sprintf("%010d 0x%03X %04X V=% X I=0x%03X SP=%02X DT=%02X ST=%02X %s",
+		r.Cycle, r.Pc, r.Opcode, r.V[:], r.I, r.Sp, r.DT, r.ST, r.Asm)
+}
+
+//Range an inclusive range of addresses or cycles
+type Range struct {
+	Start, End uint64
+}
+
+//ParseRange parses a range given as start-end, where either end can be left
+//out, or a single value. Values are decimal, or hex with a 0x or $ prefix.
+func ParseRange(s string) (*Range, error) {
+	r := &Range{End: ^uint64(0)}
+	start, end := s, s
+	if i := strings.Index(s, "-"); i >= 0 {
+		start, end = s[:i], s[i+1:]
+	}
+	var err error
+	if start != "" {
+		if r.Start, err = parseNum(start); err != nil {
+			return nil, fmt.Errorf("invalid range %q: %v", s, err)
+		}
+	}
+	if end != "" {
+		if r.End, err = parseNum(end); err != nil {
+			return nil, fmt.Errorf("invalid range %q: %v", s, err)
+		}
+	}
+	if r.End < r.Start {
+		return nil, fmt.Errorf("invalid range %q: end is before start", s)
+	}
+	return r, nil
+}
+
+func parseNum(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "$") {
+		return strconv.ParseUint(s[1:], 16, 64)
+	}
+	return strconv.ParseUint(s, 0, 64)
+}
+
+//Contains whether n is within the range, a nil range contains everything
+func (r *Range) Contains(n uint64) bool {
+	return r == nil || (n >= r.Start && n <= r.End)
+}
+
+//Writer an execution tracer writing a record of each instruction executed,
+//optionally only those within a range of addresses and cycles. Attach it to a
+//machine as its Tracer.
+type Writer struct {
+	w      *bufio.Writer
+	file   io.Closer
+	format Format
+	enc    *json.Encoder
+	err    error
+
+	//Addrs and Cycles limit the instructions traced, nil traces everything
+	Addrs  *Range
+	Cycles *Range
+}
+
+//NewWriter constructor for a tracer writing to w
+func NewWriter(w io.Writer, format Format) *Writer {
+	t := &Writer{w: bufio.NewWriter(w), format: format}
+	t.enc = json.NewEncoder(t.w)
+	return t
+}
+
+//Create constructor for a tracer writing to a new file at path, or stdout if path
+//is -
+func Create(path string, format Format) (*Writer, error) {
+	if path == "-" {
+		return NewWriter(os.Stdout, format), nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	t := NewWriter(f, format)
+	t.file = f
+	return t, nil
+}
+
+//Trace writes the record for an instruction, implementing core.TracerInterface.
+//Write errors stop the trace and are returned by Close.
+func (t *Writer) Trace(c *core.Chip8, pc, opcode uint16) {
+	if t.err != nil || !t.Addrs.Contains(uint64(pc)) || !t.Cycles.Contains(c.Cycles) {
+		return
+	}
+	r := NewRecord(c, pc, opcode)
+	if t.format == FormatJSONL {
+		t.err = t.enc.Encode(r)
+	} else {
+		_, t.err = fmt.Fprintln(t.w, r)
+	}
+}
+
+//Close flushes the trace, closing the file if created by Create
+func (t *Writer) Close() error {
+	if err := t.w.Flush(); t.err == nil {
+		t.err = err
+	}
+	if t.file != nil {
+		if err := t.file.Close(); t.err == nil {
+			t.err = err
+		}
+	}
+	return t.err
+}
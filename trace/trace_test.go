@@ -0,0 +1,129 @@
+package trace
+
+import (
+	"bytes"
+	"chip8emu/core"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func newTestChip() *core.Chip8 {
+	chip := core.NewChip8()
+	core.NewHeadlessDisplay(chip)
+	chip.SetMem(0x200, core.LoadVxFromKk|0x105)
+	chip.SetMem(0x202, core.LoadVxAddKk|0x101)
+	chip.SetMem(0x204, core.Jump|0x202)
+	return chip
+}
+
+func TestTextTrace(t *testing.T) {
+	chip := newTestChip()
+	out := &bytes.Buffer{}
+	tracer := NewWriter(out, FormatText)
+	chip.Tracer = tracer
+	chip.RunCycles(2)
+	tracer.Close()
+
+	expected := "0000000000 0x200 6105 V=00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 I=0x000 SP=0F DT=00 ST=00 MOVE V1, $5\n" +
+		"0000000001 0x202 7101 V=00 05 00 00 00 00 00 00 00 00 00 00 00 00 00 00 I=0x000 SP=0F DT=00 ST=00 ADD V1, $1\n"
+	if out.String() == expected {
+		t.Log("Text trace - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected\n%sactual\n%s", expected, out.String())
+		t.Error(msg)
+	}
+}
+
+func TestFilteredJSONLTrace(t *testing.T) {
+	chip := newTestChip()
+	out := &bytes.Buffer{}
+	tracer := NewWriter(out, FormatJSONL)
+	tracer.Addrs, _ = ParseRange("0x202")
+	tracer.Cycles, _ = ParseRange("3-6")
+	chip.Tracer = tracer
+	chip.RunCycles(8)
+	tracer.Close()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	var first Record
+	err := json.Unmarshal([]byte(lines[0]), &first)
+	if err == nil && len(lines) == 2 && first.Cycle == 3 && first.Pc == 0x202 && first.V[1] == 6 {
+		t.Log("Address and cycle filters - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected trace, error %v:\n%s", err, out.String())
+		t.Error(msg)
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		s          string
+		start, end uint64
+	}{
+		{"0x200-0x2FF", 0x200, 0x2FF},
+		{"$300", 0x300, 0x300},
+		{"1000-", 1000, ^uint64(0)},
+		{"-50", 0, 50},
+	}
+	for _, test := range tests {
+		r, err := ParseRange(test.s)
+		if err == nil && r.Start == test.start && r.End == test.end {
+			t.Log(test.s + " parsed - Test Passed")
+		} else {
+			msg := fmt.Sprintf("%s: expected %d-%d, actual %+v error %v", test.s, test.start, test.end, r, err)
+			t.Error(msg)
+		}
+	}
+	if _, err := ParseRange("10-5"); err != nil {
+		t.Log("Reversed range rejected - Test Passed")
+	} else {
+		t.Error("Expected an error for a reversed range")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	//Record a reference trace, then change the program so it diverges
+	chip := newTestChip()
+	ref := &bytes.Buffer{}
+	tracer := NewWriter(ref, FormatText)
+	chip.Tracer = tracer
+	chip.RunCycles(6)
+	tracer.Close()
+
+	chip = newTestChip()
+	cmp := &Comparer{Context: 2}
+	d, err := cmp.Compare(chip, NewReader(bytes.NewReader(ref.Bytes())))
+	if err == nil && d == nil && cmp.Steps == 6 {
+		t.Log("Matching trace - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected 6 matching steps, actual %d, divergence %+v error %v", cmp.Steps, d, err)
+		t.Error(msg)
+	}
+
+	chip = newTestChip()
+	chip.SetMem(0x202, core.LoadVxAddKk|0x102)
+	cmp = &Comparer{Context: 2}
+	d, err = cmp.Compare(chip, NewReader(bytes.NewReader(ref.Bytes())))
+	if err == nil && d != nil && d.Actual.Cycle == 1 && strings.Join(d.Diffs, " ") == "opcode" && len(d.History) == 1 {
+		t.Log("Divergent opcode - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected an opcode divergence at cycle 1, actual %+v error %v", d, err)
+		t.Error(msg)
+	}
+
+	chip = newTestChip()
+	chip.SetV(2, 9)
+	cmp = &Comparer{Context: 2}
+	d, _ = cmp.Compare(chip, NewReader(bytes.NewReader(ref.Bytes())))
+	out := &bytes.Buffer{}
+	if d != nil {
+		d.Write(out)
+	}
+	if d != nil && d.Actual.Cycle == 0 && strings.Contains(out.String(), "\tV2      0x00      0x09\n") {
+		t.Log("Divergent register reported - Test Passed")
+	} else {
+		t.Error("Expected V2 to diverge at cycle 0, output: ", out.String())
+	}
+}
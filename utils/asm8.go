@@ -3,45 +3,913 @@ package utils
 import (
 	"bufio"
 	"fmt"
-	"log"
+	"io"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 )
 
-func mainEntry() {
+//progStart the address programs are loaded at, the start of the assembled ROM
+const progStart = 0x200
 
-	file, err := os.Open("/tmp/chip8asm.asm")
+//AsmError an error found assembling a program, located by file and line
+type AsmError struct {
+	File string
+	Line int
+	Msg  string
+}
 
-	if err != nil {
-		log.Fatal(err)
+func (e *AsmError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+//AsmErrors every error found assembling a program, in source order
+type AsmErrors []*AsmError
+
+func (e AsmErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
 	}
-	defer file.Close()
+	return strings.Join(msgs, "\n")
+}
 
-	scanner := bufio.NewScanner(file)
+//asmLine a single parsed source line
+type asmLine struct {
+	file  string
+	line  int
+	text  string
+	label string
+	op    string
+	args  []string
+	addr  int
+	//expanded set for lines produced by a macro expansion
+	expanded bool
+	//skip set for lines excluded by conditional assembly
+	skip bool
+	//data the encoded bytes, set by the second pass
+	data []byte
+}
+
+//errorf builds an error located at the line
+func (l *asmLine) errorf(format string, a ...interface{}) *AsmError {
+	return &AsmError{File: l.file, Line: l.line, Msg: fmt.Sprintf(format, a...)}
+}
 
-	for scanner.Scan() {
-		line := scanner.Text()
+//assembler the state of a two pass assembly. The first pass, run as the source is
+//read, assigns addresses to every line and defines the symbols, the second encodes
+//each line.
+type assembler struct {
+	lines     []*asmLine
+	symbols   map[string]int
+	errs      AsmErrors
+	including []string
 
-		ops := strings.Split(line, " ")
+	//pc the address of the next line, conds the enclosing conditionals and
+	//skipping whether a branch not taken is being read
+	pc       int
+	conds    []*asmCondition
+	skipping bool
+	//passErrs the number of errors found by the first pass, which unlike errors
+	//reading the source don't stop the second pass
+	passErrs int
+
+	macros map[string]*asmMacro
+	//defining the macro whose body is being read
+	defining *asmMacro
+	//expansions the number of macro expansions so far, used to make labels unique
+	expansions int
+	//depth the current macro expansion nesting
+	depth int
+}
 
-		fmt.Println(ops[0])
-		fmt.Println("------")
-		fmt.Println(ops[1])
+//Program an assembled program
+type Program struct {
+	//ROM the program to be loaded at 0x200
+	ROM []byte
+	//Symbols the labels and constants defined by the program
+	Symbols map[string]int
 
+	lines []*asmLine
+}
+
+//Assemble assembles the source read from r. name is used in error messages and to
+//resolve includes. On failure the error is an AsmErrors listing every error found.
+func Assemble(r io.Reader, name string) (*Program, error) {
+	a := &assembler{symbols: make(map[string]int), macros: make(map[string]*asmMacro), pc: progStart}
+	a.parse(r, name)
+	if a.defining != nil {
+		a.errs = append(a.errs, a.defining.def.errorf("macro %s is missing endm", a.defining.name))
+	}
+	for _, cond := range a.conds {
+		a.errs = append(a.errs, cond.line.errorf("if without endif"))
+	}
+	if len(a.errs) > a.passErrs {
+		return nil, a.errs
+	}
+
+	//Carry on to the second pass after errors in the first, to report as many
+	//errors as possible in one go
+	rom := a.encode()
+	if len(a.errs) > 0 {
+		return nil, a.errs
 	}
+	return &Program{ROM: rom, Symbols: a.symbols, lines: a.lines}, nil
+}
 
+//AssembleFile assembles the source file at path, see Assemble
+func AssembleFile(path string) (*Program, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Assemble(f, path)
 }
 
-func parseLosdOps(operands string) bool {
+//parse reads the source into lines, expanding includes
+func (a *assembler) parse(r io.Reader, name string) {
+	a.including = append(a.including, name)
+	defer func() { a.including = a.including[:len(a.including)-1] }()
 
-	switch string(operands[0]) {
-	case "$":
-		fmt.Println("Hex number")
-	case "%":
-		fmt.Println("Bin number")
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		a.addLine(&asmLine{file: name, line: n, text: scanner.Text()})
+	}
+	if err := scanner.Err(); err != nil {
+		a.errs = append(a.errs, &AsmError{File: name, Msg: err.Error()})
+	}
+}
+
+//addLine parses a line, expanding includes and macros and collecting macro
+//definitions, then places it in memory. Conditionals are evaluated as the lines
+//are read, so a branch not taken defines no macros and includes no files. Every
+//line is kept, for the listing.
+func (a *assembler) addLine(l *asmLine) {
+	a.lines = append(a.lines, l)
+	l.addr = a.pc
+
+	//Macro bodies are only parsed once expanded, as they may use @ in labels
+	if a.defining != nil {
+		l.skip = a.defining.skipped
+		switch macroBodyOp(l.text) {
+		case "MACRO":
+			a.errs = append(a.errs, l.errorf("macro definitions can't be nested"))
+		case "ENDM":
+			a.defining = nil
+		default:
+			a.defining.body = append(a.defining.body, l.text)
+		}
+		return
+	}
+
+	if err := parseLine(l, l.text); err != nil {
+		a.errs = append(a.errs, err)
+		l.label, l.op, l.args = "", "", nil
+		return
+	}
+
+	switch l.op {
+	case "IF", "ELSE", "ENDIF":
+		a.condition(l)
+		return
+	}
+	if a.skipping {
+		l.skip = true
+		if l.op == "MACRO" {
+			//Read past the body without defining the macro
+			a.defining = &asmMacro{def: l, skipped: true}
+			if fields := strings.Fields(strings.Join(l.args, " ")); len(fields) > 0 {
+				a.defining.name = fields[0]
+			}
+		}
+		return
+	}
+
+	switch l.op {
+	case "MACRO":
+		a.defineMacro(l)
+		l.label, l.op, l.args = "", "", nil
+	case "ENDM":
+		a.errs = append(a.errs, l.errorf("endm without macro"))
+	case "INCLUDE":
+		//Any label on the include line refers to the start of the included file
+		l.op = ""
+		a.place(l)
+		a.include(l)
 	default:
-		fmt.Println("Decimal number")
+		if m, exists := a.macros[l.op]; exists {
+			//Any label on the macro line refers to the start of the expansion
+			l.op = ""
+			a.place(l)
+			a.expand(l, m)
+			return
+		}
+		a.place(l)
+	}
+}
+
+//include parses the file named by an include directive, relative to the
+//including file
+func (a *assembler) include(l *asmLine) {
+	if len(l.args) != 1 {
+		a.errs = append(a.errs, l.errorf("include expects a file name"))
+		return
+	}
+	path, err := strconv.Unquote(l.args[0])
+	if err != nil {
+		path = l.args[0]
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(l.file), path)
+	}
+	for _, name := range a.including {
+		if name == path {
+			a.errs = append(a.errs, l.errorf("recursive include of %s", path))
+			return
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		a.errs = append(a.errs, l.errorf("%v", err))
+		return
+	}
+	defer f.Close()
+	a.parse(f, path)
+}
+
+//asmMacro a macro definition, the body is substituted when the macro is used
+type asmMacro struct {
+	name   string
+	params []string
+	body   []string
+	def    *asmLine
+	//skipped set for a definition in a branch not taken, which is discarded
+	skipped bool
+}
+
+//maxMacroDepth limits nested macro expansion, catching recursive macros
+const maxMacroDepth = 16
+
+//defineMacro starts a macro definition, "macro name param, param..."
+func (a *assembler) defineMacro(l *asmLine) {
+	if len(l.args) == 0 {
+		a.errs = append(a.errs, l.errorf("macro expects a name"))
+		return
+	}
+	fields := strings.Fields(l.args[0])
+	name := strings.ToUpper(fields[0])
+	params := fields[1:]
+	if len(params) > 1 {
+		a.errs = append(a.errs, l.errorf("macro parameters must be separated by commas"))
+		return
+	}
+	params = append(params, l.args[1:]...)
+	for _, param := range params {
+		if !isSymbol(param) {
+			a.errs = append(a.errs, l.errorf("invalid macro parameter %q", param))
+			return
+		}
+	}
+	if !isSymbol(fields[0]) {
+		a.errs = append(a.errs, l.errorf("invalid macro name %q", fields[0]))
+		return
+	}
+	if _, exists := a.macros[name]; exists {
+		a.errs = append(a.errs, l.errorf("macro %s redefined", fields[0]))
+	}
+
+	m := &asmMacro{name: fields[0], params: params, def: l}
+	a.macros[name] = m
+	a.defining = m
+}
+
+//macroBodyOp gets the operation of a line within a macro definition, without
+//otherwise parsing it
+func macroBodyOp(text string) string {
+	fields := strings.Fields(stripComment(text))
+	if len(fields) > 0 && strings.HasSuffix(fields[0], ":") {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
+
+//expand adds the lines of a macro's body, with its parameters replaced by the
+//arguments given and each @ replaced by a number unique to the expansion
+func (a *assembler) expand(l *asmLine, m *asmMacro) {
+	if len(l.args) != len(m.params) {
+		a.errs = append(a.errs, l.errorf("macro %s expects %d arguments, found %d", m.name, len(m.params), len(l.args)))
+		return
+	}
+	if a.depth >= maxMacroDepth {
+		a.errs = append(a.errs, l.errorf("macro %s nested too deeply", m.name))
+		return
+	}
+
+	a.expansions++
+	a.depth++
+	for _, text := range m.body {
+		text = substitute(text, m.params, l.args, fmt.Sprintf("_%d", a.expansions))
+		a.addLine(&asmLine{file: l.file, line: l.line, text: text, expanded: true})
+	}
+	a.depth--
+}
+
+//substitute replaces whole word occurrences of the params in text with args and
+//each @ with unique, leaving strings unchanged
+func substitute(text string, params, args []string, unique string) string {
+	var out strings.Builder
+	for i := 0; i < len(text); {
+		if text[i] == '"' {
+			end := len(text)
+			if n := strings.IndexByte(text[i+1:], '"'); n >= 0 {
+				end = i + n + 2
+			}
+			out.WriteString(text[i:end])
+			i = end
+			continue
+		}
+		if text[i] == '@' {
+			out.WriteString(unique)
+			i++
+			continue
+		}
+		if !isExprChar(text[i], false) {
+			out.WriteByte(text[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(text) && isExprChar(text[j], false) {
+			j++
+		}
+		word := text[i:j]
+		for k, param := range params {
+			if word == param {
+				word = args[k]
+				break
+			}
+		}
+		out.WriteString(word)
+		i = j
+	}
+	return out.String()
+}
+
+//parseLine splits a source line into an optional label, an operation and its
+//comma separated arguments, e.g. "loop: ADD V0, 1 ; comment"
+func parseLine(l *asmLine, text string) *AsmError {
+	text = strings.TrimSpace(stripComment(text))
+	if text == "" {
+		return nil
+	}
+
+	fields := strings.Fields(text)
+	if strings.HasSuffix(fields[0], ":") {
+		l.label = strings.TrimSuffix(fields[0], ":")
+		text = strings.TrimSpace(text[len(fields[0]):])
+		fields = fields[1:]
+	} else if len(fields) > 1 && strings.EqualFold(fields[1], "equ") {
+		//NAME EQU value
+		l.label = fields[0]
+		text = strings.TrimSpace(text[len(fields[0]):])
+		fields = fields[1:]
+	}
+	if l.label != "" && !isSymbol(l.label) {
+		return l.errorf("invalid label %q", l.label)
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	l.op = strings.ToUpper(fields[0])
+	args, err := splitArgs(strings.TrimSpace(text[len(fields[0]):]))
+	if err != nil {
+		return l.errorf("%v", err)
+	}
+	l.args = args
+	return nil
+}
+
+//stripComment removes a ; comment, ignoring any ; within a string
+func stripComment(text string) string {
+	inString := false
+	for i, c := range text {
+		switch {
+		case c == '"':
+			inString = !inString
+		case c == ';' && !inString:
+			return text[:i]
+		}
+	}
+	return text
+}
+
+//splitArgs splits comma separated arguments, ignoring any commas within a string
+func splitArgs(text string) ([]string, error) {
+	if text == "" {
+		return nil, nil
+	}
+	var args []string
+	inString := false
+	start := 0
+	for i, c := range text {
+		switch {
+		case c == '"':
+			inString = !inString
+		case c == ',' && !inString:
+			args = append(args, strings.TrimSpace(text[start:i]))
+			start = i + 1
+		}
+	}
+	if inString {
+		return nil, fmt.Errorf("unterminated string")
 	}
+	args = append(args, strings.TrimSpace(text[start:]))
+	for _, arg := range args {
+		if arg == "" {
+			return nil, fmt.Errorf("missing argument")
+		}
+	}
+	return args, nil
+}
 
+//isSymbol whether name is a valid label or constant name, which can't clash with
+//a register name
+func isSymbol(name string) bool {
+	if name == "" || isRegister(name) {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_' || c == '.' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
 	return true
 }
+
+//isRegister whether name is I or one of the V registers
+func isRegister(name string) bool {
+	if strings.EqualFold(name, "I") {
+		return true
+	}
+	_, ok := parseVReg(name)
+	return ok
+}
+
+//parseVReg parses a V register name, V0 to VF
+func parseVReg(name string) (uint16, bool) {
+	if len(name) != 2 || (name[0] != 'V' && name[0] != 'v') {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(name[1:], 16, 4)
+	return uint16(n), err == nil
+}
+
+//asmCondition an if block being read
+type asmCondition struct {
+	line *asmLine
+	//parent whether the enclosing code is assembled, taken whether the if branch is
+	parent bool
+	taken  bool
+	inElse bool
+}
+
+//condition handles an if, else or endif line, deciding whether the lines that
+//follow are skipped
+func (a *assembler) condition(l *asmLine) {
+	switch l.op {
+	case "IF":
+		cond := &asmCondition{line: l, parent: !a.skipping}
+		if cond.parent {
+			if len(l.args) != 1 {
+				a.passError(l.errorf("if expects a single condition"))
+			} else if val, err := a.eval(l, l.args[0]); err != nil {
+				a.passError(err)
+			} else {
+				cond.taken = val != 0
+			}
+		}
+		a.conds = append(a.conds, cond)
+		a.skipping = !cond.taken
+	case "ELSE":
+		if len(a.conds) == 0 || a.conds[len(a.conds)-1].inElse {
+			a.passError(l.errorf("else without if"))
+			return
+		}
+		cond := a.conds[len(a.conds)-1]
+		cond.inElse = true
+		a.skipping = !cond.parent || cond.taken
+	case "ENDIF":
+		if len(a.conds) == 0 {
+			a.passError(l.errorf("endif without if"))
+			return
+		}
+		a.skipping = !a.conds[len(a.conds)-1].parent
+		a.conds = a.conds[:len(a.conds)-1]
+	}
+}
+
+//passError adds an error found by the first pass
+func (a *assembler) passError(err *AsmError) {
+	a.errs = append(a.errs, err)
+	a.passErrs++
+}
+
+//place the first pass for a line, defining its label or constant and placing it
+//in memory
+func (a *assembler) place(l *asmLine) {
+	if l.op == "EQU" {
+		if l.label == "" || len(l.args) != 1 {
+			a.passError(l.errorf("equ expects a name and a single value"))
+			return
+		}
+		val, err := a.eval(l, l.args[0])
+		if err != nil {
+			//Still define the constant, to avoid an error for every use of it
+			a.passError(err)
+		}
+		a.define(l, val)
+		return
+	}
+
+	if l.op == "ORG" {
+		if len(l.args) != 1 {
+			a.passError(l.errorf("org expects an address"))
+			return
+		}
+		val, err := a.eval(l, l.args[0])
+		if err != nil {
+			a.passError(err)
+			return
+		}
+		if val < progStart || val > 0xFFFF {
+			a.passError(l.errorf("org address 0x%X outside program memory 0x200-0xFFFF", val))
+			return
+		}
+		a.pc = val
+		l.addr = a.pc
+	}
+
+	if l.label != "" {
+		a.define(l, a.pc)
+	}
+
+	switch l.op {
+	case "", "ORG":
+	case "DB":
+		n := 0
+		for _, arg := range l.args {
+			if s, err := strconv.Unquote(arg); err == nil {
+				n += len(s)
+			} else {
+				n++
+			}
+		}
+		a.pc += n
+	case "DW":
+		a.pc += 2 * len(l.args)
+	case "LONGI":
+		a.pc += 4
+	default:
+		a.pc += 2
+	}
+}
+
+//define adds a symbol, labels and constants share the same namespace
+func (a *assembler) define(l *asmLine, val int) {
+	if _, exists := a.symbols[l.label]; exists {
+		a.passError(l.errorf("%s redefined", l.label))
+		return
+	}
+	a.symbols[l.label] = val
+}
+
+//eval evaluates an argument as an expression, see evalExpr. Numbers are decimal,
+//hex with a $ or 0x prefix, or binary with a % prefix.
+func (a *assembler) eval(l *asmLine, arg string) (int, *AsmError) {
+	val, err := evalExpr(arg, a.symbols, l.addr)
+	if err != nil {
+		return 0, l.errorf("%v", err)
+	}
+	return val, nil
+}
+
+//parseLiteral parses a number literal, see eval
+func parseLiteral(s string) (int, bool) {
+	base := 10
+	switch {
+	case strings.HasPrefix(s, "$"):
+		base, s = 16, s[1:]
+	case strings.HasPrefix(s, "%"):
+		base, s = 2, s[1:]
+	case strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X"):
+		base, s = 16, s[2:]
+	}
+	n, err := strconv.ParseUint(s, base, 32)
+	return int(n), err == nil
+}
+
+//encode the second pass, assembling each line into the ROM image
+func (a *assembler) encode() []byte {
+	var mem [0x10000]byte
+	var used [0x10000]bool
+	end := progStart
+
+	for _, l := range a.lines {
+		if l.skip {
+			continue
+		}
+		data, err := a.encodeLine(l)
+		if err != nil {
+			a.errs = append(a.errs, err)
+			continue
+		}
+		l.data = data
+		if l.addr+len(data) > len(mem) {
+			a.errs = append(a.errs, l.errorf("program extends past the end of memory"))
+			continue
+		}
+		for i, b := range data {
+			if used[l.addr+i] {
+				a.errs = append(a.errs, l.errorf("overlaps earlier code or data at 0x%03X", l.addr+i))
+				break
+			}
+			used[l.addr+i] = true
+			mem[l.addr+i] = b
+		}
+		if l.addr+len(data) > end {
+			end = l.addr + len(data)
+		}
+	}
+	return append([]byte(nil), mem[progStart:end]...)
+}
+
+//encodeLine assembles a single line
+func (a *assembler) encodeLine(l *asmLine) ([]byte, *AsmError) {
+	switch l.op {
+	case "", "EQU", "ORG", "IF", "ELSE", "ENDIF":
+		return nil, nil
+	case "DB":
+		var data []byte
+		for _, arg := range l.args {
+			if s, err := strconv.Unquote(arg); err == nil {
+				data = append(data, s...)
+				continue
+			}
+			val, err := a.value(l, arg, 0xFF)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, byte(val))
+		}
+		return data, nil
+	case "DW":
+		var data []byte
+		for _, arg := range l.args {
+			val, err := a.value(l, arg, 0xFFFF)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, byte(val>>8), byte(val))
+		}
+		return data, nil
+	case "LONGI":
+		if err := l.expectArgs(1); err != nil {
+			return nil, err
+		}
+		val, err := a.value(l, l.args[0], 0xFFFF)
+		if err != nil {
+			return nil, err
+		}
+		return []byte{0xF0, 0x00, byte(val >> 8), byte(val)}, nil
+	}
+
+	inst, err := a.encodeInst(l)
+	if err != nil {
+		return nil, err
+	}
+	return []byte{byte(inst >> 8), byte(inst)}, nil
+}
+
+//Instructions taking no operands
+var asmNoArgs = map[string]uint16{
+	"CLS":     0x00E0,
+	"RTS":     0x00EE,
+	"SCRIGHT": 0x00FB,
+	"SCLEFT":  0x00FC,
+	"EXIT":    0x00FD,
+	"LOW":     0x00FE,
+	"HIGH":    0x00FF,
+	"AUDIO":   0xF002,
+}
+
+//Instructions taking a single V register operand
+var asmVx = map[string]uint16{
+	"SKPR":  0xE09E,
+	"SKUP":  0xE0A1,
+	"MOVDT": 0xF007,
+	"KEYPR": 0xF00A,
+	"SETDT": 0xF015,
+	"SETST": 0xF018,
+	"ADDI":  0xF01E,
+	"FONT":  0xF029,
+	"XFONT": 0xF030,
+	"BCD":   0xF033,
+	"PITCH": 0xF03A,
+	"STORE": 0xF055,
+	"LOAD":  0xF065,
+	"STRPL": 0xF075,
+	"LDRPL": 0xF085,
+}
+
+//Instructions taking two V register operands
+var asmVxVy = map[string]uint16{
+	"SAVE":    0x5002,
+	"RESTORE": 0x5003,
+	"OR":      0x8001,
+	"AND":     0x8002,
+	"XOR":     0x8003,
+	"SUB":     0x8005,
+	"RSUB":    0x8007,
+}
+
+//Instructions taking a V register and either a second register or a byte, given
+//as the register form then the byte form
+var asmVxVyOrByte = map[string][2]uint16{
+	"SKEQ": {0x5000, 0x3000},
+	"SKNE": {0x9000, 0x4000},
+	"MOVE": {0x8000, 0x6000},
+	"ADD":  {0x8004, 0x7000},
+}
+
+//encodeInst assembles a single two byte instruction
+func (a *assembler) encodeInst(l *asmLine) (uint16, *AsmError) {
+	if op, exists := asmNoArgs[l.op]; exists {
+		return op, l.expectArgs(0)
+	}
+
+	if op, exists := asmVx[l.op]; exists {
+		if err := l.expectArgs(1); err != nil {
+			return 0, err
+		}
+		x, err := l.reg(l.args[0])
+		return op | x<<8, err
+	}
+
+	if op, exists := asmVxVy[l.op]; exists {
+		if err := l.expectArgs(2); err != nil {
+			return 0, err
+		}
+		x, err := l.reg(l.args[0])
+		if err != nil {
+			return 0, err
+		}
+		y, err := l.reg(l.args[1])
+		return op | x<<8 | y<<4, err
+	}
+
+	if ops, exists := asmVxVyOrByte[l.op]; exists {
+		if l.op == "MOVE" && len(l.args) == 2 && strings.EqualFold(l.args[0], "I") {
+			nnn, err := a.value(l, l.args[1], 0xFFF)
+			return 0xA000 | nnn, err
+		}
+		if err := l.expectArgs(2); err != nil {
+			return 0, err
+		}
+		x, err := l.reg(l.args[0])
+		if err != nil {
+			return 0, err
+		}
+		if y, ok := parseVReg(l.args[1]); ok {
+			return ops[0] | x<<8 | y<<4, nil
+		}
+		kk, err := a.value(l, l.args[1], 0xFF)
+		return ops[1] | x<<8 | kk, err
+	}
+
+	switch l.op {
+	case "SCDOWN", "SCUP", "PLANE":
+		if err := l.expectArgs(1); err != nil {
+			return 0, err
+		}
+		n, err := a.value(l, l.args[0], 0xF)
+		switch l.op {
+		case "SCDOWN":
+			return 0x00C0 | n, err
+		case "SCUP":
+			return 0x00D0 | n, err
+		}
+		return 0xF001 | n<<8, err
+	case "SHR", "SHL":
+		//The Y register only matters with the shift-vy quirk, it defaults to X
+		//so the result is the same either way.
+		if len(l.args) != 1 && len(l.args) != 2 {
+			return 0, l.errorf("%s expects 1 or 2 operands, found %d", l.op, len(l.args))
+		}
+		x, err := l.reg(l.args[0])
+		if err != nil {
+			return 0, err
+		}
+		y := x
+		if len(l.args) == 2 {
+			if y, err = l.reg(l.args[1]); err != nil {
+				return 0, err
+			}
+		}
+		op := uint16(0x8006)
+		if l.op == "SHL" {
+			op = 0x800E
+		}
+		return op | x<<8 | y<<4, nil
+	case "JSR":
+		if err := l.expectArgs(1); err != nil {
+			return 0, err
+		}
+		nnn, err := a.value(l, l.args[0], 0xFFF)
+		return 0x2000 | nnn, err
+	case "JMP":
+		//JMP addr, or JMP V0 + addr / JMP V0, addr
+		args := l.args
+		if len(args) == 1 {
+			if i := strings.Index(args[0], "+"); i >= 0 && strings.EqualFold(strings.TrimSpace(args[0][:i]), "V0") {
+				args = []string{"V0", strings.TrimSpace(args[0][i+1:])}
+			}
+		}
+		switch {
+		case len(args) == 1:
+			nnn, err := a.value(l, args[0], 0xFFF)
+			return 0x1000 | nnn, err
+		case len(args) == 2 && strings.EqualFold(args[0], "V0"):
+			nnn, err := a.value(l, args[1], 0xFFF)
+			return 0xB000 | nnn, err
+		}
+		return 0, l.errorf("JMP expects an address or V0 + address")
+	case "RAND":
+		if err := l.expectArgs(2); err != nil {
+			return 0, err
+		}
+		x, err := l.reg(l.args[0])
+		if err != nil {
+			return 0, err
+		}
+		kk, err := a.value(l, l.args[1], 0xFF)
+		return 0xC000 | x<<8 | kk, err
+	case "DRAW":
+		if err := l.expectArgs(3); err != nil {
+			return 0, err
+		}
+		x, err := l.reg(l.args[0])
+		if err != nil {
+			return 0, err
+		}
+		y, err := l.reg(l.args[1])
+		if err != nil {
+			return 0, err
+		}
+		n, err := a.value(l, l.args[2], 0xF)
+		return 0xD000 | x<<8 | y<<4 | n, err
+	}
+	return 0, l.errorf("unknown instruction or directive %s", l.op)
+}
+
+//expectArgs checks the line has n operands
+func (l *asmLine) expectArgs(n int) *AsmError {
+	if len(l.args) != n {
+		return l.errorf("%s expects %d operands, found %d", l.op, n, len(l.args))
+	}
+	return nil
+}
+
+//reg parses a V register operand
+func (l *asmLine) reg(arg string) (uint16, *AsmError) {
+	x, ok := parseVReg(arg)
+	if !ok {
+		return 0, l.errorf("expected a register V0-VF, found %q", arg)
+	}
+	return x, nil
+}
+
+//value evaluates an operand, checking it is within 0 to max
+func (a *assembler) value(l *asmLine, arg string, max int) (uint16, *AsmError) {
+	val, err := a.eval(l, arg)
+	if err != nil {
+		return 0, err
+	}
+	//Bytes may be given as negative numbers, stored as two's complement
+	if max == 0xFF && val < 0 && val >= -0x80 {
+		val &= 0xFF
+	}
+	if val < 0 || val > max {
+		return 0, l.errorf("value %s = 0x%X out of range, expected 0 to 0x%X", arg, val, max)
+	}
+	return uint16(val), nil
+}
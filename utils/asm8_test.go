@@ -0,0 +1,281 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAssembleInstructions(t *testing.T) {
+	src := `
+; every instruction form
+start:	CLS
+	RTS
+	SCDOWN	4
+	SCUP	2
+	SCRIGHT
+	SCLEFT
+	EXIT
+	LOW
+	HIGH
+	JMP	start
+	JMP	V0 + $300
+	JSR	sub
+	SKEQ	V1, $12
+	SKNE	V2, 34
+	SKEQ	V3, V4
+	SKNE	V5, V6
+	SAVE	V1, V4
+	RESTORE	V4, V1
+	MOVE	V7, %10101010
+	MOVE	V8, V9
+	MOVE	I, sprite
+	ADD	VA, 1
+	ADD	VB, VC
+	OR	V1, V2
+	AND	V1, V2
+	XOR	V1, V2
+	SUB	V1, V2
+	RSUB	V1, V2
+	SHR	V3
+	SHL	V3, V4
+	RAND	VD, 0x0F
+	DRAW	V0, V1, 5
+	SKPR	VE
+	SKUP	VF
+	LONGI	$1234
+	PLANE	3
+	AUDIO
+	PITCH	V2
+	MOVDT	V3
+	KEYPR	V4
+	SETDT	V5
+	SETST	V6
+	ADDI	V7
+	FONT	V8
+	XFONT	V9
+	BCD	VA
+	STORE	VB
+	LOAD	VC
+	STRPL	VD
+	LDRPL	VE
+sub:	rts
+sprite:	db	$F0, %10010000, 144, "AB"
+	dw	$1234, sprite
+`
+	expected := []uint16{
+		0x00E0, 0x00EE, 0x00C4, 0x00D2, 0x00FB, 0x00FC, 0x00FD, 0x00FE, 0x00FF,
+		0x1200, 0xB300, 0x2266,
+		0x3112, 0x4222, 0x5340, 0x9560, 0x5142, 0x5413,
+		0x67AA, 0x8890, 0xA268, 0x7A01, 0x8BC4,
+		0x8121, 0x8122, 0x8123, 0x8125, 0x8127, 0x8336, 0x834E,
+		0xCD0F, 0xD015, 0xEE9E, 0xEFA1, 0xF000, 0x1234, 0xF301, 0xF002, 0xF23A,
+		0xF307, 0xF40A, 0xF515, 0xF618, 0xF71E, 0xF829, 0xF930, 0xFA33, 0xFB55,
+		0xFC65, 0xFD75, 0xFE85,
+		0x00EE,
+		0xF090, 0x9041, 0x4212, 0x3402, 0x6800,
+	}
+	var want []byte
+	for _, w := range expected {
+		want = append(want, byte(w>>8), byte(w))
+	}
+	//The data after the sprite label is an odd length
+	want = want[:len(want)-1]
+
+	prog, err := Assemble(strings.NewReader(src), "test.asm")
+	if err != nil {
+		t.Fatal("Assemble failed: ", err)
+	}
+	if bytes.Equal(prog.ROM, want) {
+		t.Log("Assemble every instruction form - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected\n% X\nactual\n% X", want, prog.ROM)
+		t.Error(msg)
+	}
+}
+
+func TestAssembleDirectives(t *testing.T) {
+	dir, err := ioutil.TempDir("", "asm8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "consts.inc"), []byte("SPEED equ 3\n"), 0644)
+	src := `	include "consts.inc"
+	MOVE	V0, SPEED
+	JMP	data
+	org	$210
+data:	db	SPEED
+`
+	path := filepath.Join(dir, "main.asm")
+	ioutil.WriteFile(path, []byte(src), 0644)
+
+	prog, err := AssembleFile(path)
+	want := []byte{0x60, 0x03, 0x12, 0x10, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x03}
+	if err == nil && bytes.Equal(prog.ROM, want) {
+		t.Log("include, equ and org - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected % X, actual %+v, error %v", want, prog, err)
+		t.Error(msg)
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	src := `	CLS
+	MOVE	V0, 256
+	JMP	nowhere
+	FROB	V1
+loop:	ADD	V1, 1
+loop:	DRAW	V1, V2
+`
+	_, err := Assemble(strings.NewReader(src), "bad.asm")
+	errs, ok := err.(AsmErrors)
+	expected := []string{
+		"bad.asm:6: loop redefined",
+		"bad.asm:2: value 256 = 0x100 out of range, expected 0 to 0xFF",
+		"bad.asm:3: undefined symbol nowhere",
+		"bad.asm:4: unknown instruction or directive FROB",
+		"bad.asm:6: DRAW expects 3 operands, found 2",
+	}
+	if ok && err.Error() == strings.Join(expected, "\n") {
+		t.Log("Line numbered errors - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected errors %d:\n%v", len(errs), err)
+		t.Error(msg)
+	}
+}
+
+func TestAssembleExpressions(t *testing.T) {
+	src := `BASE	equ	$300
+	MOVE	V0, hi(table + 2)
+	MOVE	V1, lo(table + 2)
+	MOVE	V2, (1 << 4) | %0011
+	ADD	V3, -1
+	MOVE	I, BASE + 2 * 8
+	JMP	$ + 2
+	org	BASE
+table:	dw	table - BASE, ~0 & $FF
+`
+	prog, err := Assemble(strings.NewReader(src), "expr.asm")
+	if err != nil {
+		t.Fatal("Assemble failed: ", err)
+	}
+	want := []byte{0x60, 0x03, 0x61, 0x02, 0x62, 0x13, 0x73, 0xFF, 0xA3, 0x10, 0x12, 0x0C}
+	if bytes.Equal(prog.ROM[:len(want)], want) && bytes.Equal(prog.ROM[0x100:], []byte{0, 0, 0, 0xFF}) {
+		t.Log("Expressions - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected ROM % X", prog.ROM)
+		t.Error(msg)
+	}
+}
+
+func TestAssembleMacrosAndConditionals(t *testing.T) {
+	src := `DEBUG	equ	0
+	macro	wait reg, frames
+	MOVE	reg, frames
+	SETDT	reg
+loop@:	MOVDT	reg
+	SKEQ	reg, 0
+	JMP	loop@
+	endm
+
+	if	DEBUG
+	EXIT
+	else
+	wait	V0, 30
+	endif
+	if	DEBUG == 0
+	wait	V1, 5
+	endif
+`
+	prog, err := Assemble(strings.NewReader(src), "macro.asm")
+	if err != nil {
+		t.Fatal("Assemble failed: ", err)
+	}
+	want := []byte{
+		0x60, 0x1E, 0xF0, 0x15, 0xF0, 0x07, 0x30, 0x00, 0x12, 0x04,
+		0x61, 0x05, 0xF1, 0x15, 0xF1, 0x07, 0x31, 0x00, 0x12, 0x0E,
+	}
+	if bytes.Equal(prog.ROM, want) && prog.Symbols["loop_1"] == 0x204 && prog.Symbols["loop_2"] == 0x20E {
+		t.Log("Macros with unique labels and conditionals - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected ROM % X, symbols %v", prog.ROM, prog.Symbols)
+		t.Error(msg)
+	}
+
+	var listing bytes.Buffer
+	prog.WriteListing(&listing)
+	expected := "0200  60 1E           13+ \tMOVE\tV0, 30\n"
+	if strings.Contains(listing.String(), expected) {
+		t.Log("Listing - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected listing to contain %q:\n%s", expected, listing.String())
+		t.Error(msg)
+	}
+
+	var symbols bytes.Buffer
+	prog.WriteSymbols(&symbols)
+	if symbols.String() == "DEBUG\tequ\t$0000\nloop_1\tequ\t$0204\nloop_2\tequ\t$020E\n" {
+		t.Log("Symbol table - Test Passed")
+	} else {
+		t.Error("Unexpected symbol table:\n", symbols.String())
+	}
+}
+
+func TestAssembleSkippedBranches(t *testing.T) {
+	src := `	if	0
+	macro	m
+	CLS
+	endm
+	include	"missing.asm"
+	else
+	macro	m
+	RTS
+	endm
+	endif
+	m
+	if	1
+	else
+	macro	unused
+	endm
+	endif
+`
+	prog, err := Assemble(strings.NewReader(src), "skip.asm")
+	if err == nil && bytes.Equal(prog.ROM, []byte{0x00, 0xEE}) {
+		t.Log("Branches not taken define no macros and include no files - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected ROM %v, error %v", prog, err)
+		t.Error(msg)
+	}
+
+	src = `	if	0
+	macro	m
+	CLS
+`
+	if _, err := Assemble(strings.NewReader(src), "skip.asm"); err != nil &&
+		err.Error() == "skip.asm:2: macro m is missing endm\nskip.asm:1: if without endif" {
+		t.Log("Unterminated skipped macro reported - Test Passed")
+	} else {
+		t.Error("Unexpected error ", err)
+	}
+}
+
+func TestAssembleMacroStrings(t *testing.T) {
+	src := `	macro	m x
+	db	x, "x@"
+	endm
+	m	5
+`
+	prog, err := Assemble(strings.NewReader(src), "strings.asm")
+	if err == nil && string(prog.ROM) == "\x05x@" {
+		t.Log("Macro parameters and @ not substituted in strings - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected ROM %v, error %v", prog, err)
+		t.Error(msg)
+	}
+}
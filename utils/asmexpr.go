@@ -0,0 +1,213 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+//exprParser evaluates a constant expression by recursive descent. In order of
+//increasing precedence the operators are the comparisons (== != < <= > >=), which
+//give 1 or 0, then | ^ & (<< >>) (+ -) (* /), then the unary - ~ and the hi() and
+//lo() functions. $ on its own is the address of the current line.
+type exprParser struct {
+	s       string
+	pos     int
+	symbols map[string]int
+	pc      int
+}
+
+//evalExpr evaluates expr using the given symbols, with pc as the value of $
+func evalExpr(expr string, symbols map[string]int, pc int) (int, error) {
+	p := &exprParser{s: expr, symbols: symbols, pc: pc}
+	val, err := p.binary(0)
+	if err != nil {
+		return 0, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.s) {
+		return 0, fmt.Errorf("unexpected %q in expression %q", p.s[p.pos:], expr)
+	}
+	return val, nil
+}
+
+//exprOps the binary operators by precedence level, lowest first
+var exprOps = [][]string{
+	{"==", "!=", "<", "<=", ">", ">="},
+	{"|"},
+	{"^"},
+	{"&"},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/"},
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+//binary parses a sequence of operands joined by the operators of the given
+//precedence level or higher
+func (p *exprParser) binary(level int) (int, error) {
+	if level == len(exprOps) {
+		return p.unary()
+	}
+	val, err := p.binary(level + 1)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		p.skipSpace()
+		op := ""
+		for _, o := range exprOps[level] {
+			if strings.HasPrefix(p.s[p.pos:], o) && len(o) > len(op) {
+				op = o
+			}
+		}
+		if op == "" {
+			return val, nil
+		}
+		p.pos += len(op)
+		rhs, err := p.binary(level + 1)
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case "==":
+			val = boolVal(val == rhs)
+		case "!=":
+			val = boolVal(val != rhs)
+		case "<":
+			val = boolVal(val < rhs)
+		case "<=":
+			val = boolVal(val <= rhs)
+		case ">":
+			val = boolVal(val > rhs)
+		case ">=":
+			val = boolVal(val >= rhs)
+		case "|":
+			val |= rhs
+		case "^":
+			val ^= rhs
+		case "&":
+			val &= rhs
+		case "<<":
+			val <<= uint(rhs)
+		case ">>":
+			val >>= uint(rhs)
+		case "+":
+			val += rhs
+		case "-":
+			val -= rhs
+		case "*":
+			val *= rhs
+		case "/":
+			if rhs == 0 {
+				return 0, fmt.Errorf("division by zero in expression %q", p.s)
+			}
+			val /= rhs
+		}
+	}
+}
+
+func (p *exprParser) unary() (int, error) {
+	p.skipSpace()
+	if p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case '-':
+			p.pos++
+			val, err := p.unary()
+			return -val, err
+		case '~':
+			p.pos++
+			val, err := p.unary()
+			return ^val, err
+		case '+':
+			p.pos++
+			return p.unary()
+		}
+	}
+	return p.primary()
+}
+
+func (p *exprParser) primary() (int, error) {
+	p.skipSpace()
+	if p.pos >= len(p.s) {
+		return 0, fmt.Errorf("missing value in expression %q", p.s)
+	}
+
+	if p.s[p.pos] == '(' {
+		p.pos++
+		val, err := p.binary(0)
+		if err != nil {
+			return 0, err
+		}
+		return val, p.expect(')')
+	}
+
+	start := p.pos
+	for p.pos < len(p.s) && isExprChar(p.s[p.pos], p.pos == start) {
+		p.pos++
+	}
+	tok := p.s[start:p.pos]
+
+	switch {
+	case tok == "":
+		return 0, fmt.Errorf("unexpected %q in expression %q", p.s[p.pos:], p.s)
+	case tok == "$":
+		return p.pc, nil
+	case strings.EqualFold(tok, "hi") || strings.EqualFold(tok, "lo"):
+		p.skipSpace()
+		if p.pos < len(p.s) && p.s[p.pos] == '(' {
+			p.pos++
+			val, err := p.binary(0)
+			if err != nil {
+				return 0, err
+			}
+			if strings.EqualFold(tok, "hi") {
+				val >>= 8
+			}
+			return val & 0xFF, p.expect(')')
+		}
+	}
+
+	if n, ok := parseLiteral(tok); ok {
+		return n, nil
+	}
+	if val, exists := p.symbols[tok]; exists {
+		return val, nil
+	}
+	if isSymbol(tok) {
+		return 0, fmt.Errorf("undefined symbol %s", tok)
+	}
+	return 0, fmt.Errorf("invalid value %q", tok)
+}
+
+func boolVal(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (p *exprParser) expect(c byte) error {
+	p.skipSpace()
+	if p.pos >= len(p.s) || p.s[p.pos] != c {
+		return fmt.Errorf("expected %q in expression %q", c, p.s)
+	}
+	p.pos++
+	return nil
+}
+
+//isExprChar whether c can be part of a number or symbol, the $ and % literal
+//prefixes can only appear first
+func isExprChar(c byte, first bool) bool {
+	switch {
+	case c == '_' || c == '.' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9'):
+		return true
+	case c == '$' || c == '%':
+		return first
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+//listBytes the number of encoded bytes shown on each line of a listing
+const listBytes = 4
+
+//WriteListing writes a listing of the program, giving the address, encoded bytes
+//and line number of each source line. Lines from a macro expansion are marked with
+//a +, lines excluded by conditional assembly are shown without an address.
+func (p *Program) WriteListing(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	file := ""
+	for _, l := range p.lines {
+		if l.file != file {
+			file = l.file
+			fmt.Fprintf(bw, "; %s\n", file)
+		}
+
+		marker := " "
+		if l.expanded {
+			marker = "+"
+		}
+		addr := fmt.Sprintf("%04X", l.addr)
+		if l.skip {
+			addr = "    "
+		}
+
+		data := l.data
+		for first := true; first || len(data) > 0; first = false {
+			n := len(data)
+			if n > listBytes {
+				n = listBytes
+			}
+			hex := fmt.Sprintf("% X", data[:n])
+			if first {
+				fmt.Fprintf(bw, "%s  %-*s %5d%s %s\n", addr, listBytes*3, hex, l.line, marker, l.text)
+			} else {
+				fmt.Fprintf(bw, "%04X  %s\n", l.addr+len(l.data)-len(data), hex)
+			}
+			data = data[n:]
+		}
+	}
+	return bw.Flush()
+}
+
+//WriteSymbols writes the program's symbols in address order, as equ directives so
+//they can be included by other programs
+func (p *Program) WriteSymbols(w io.Writer) error {
+	names := make([]string, 0, len(p.Symbols))
+	for name := range p.Symbols {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := p.Symbols[names[i]], p.Symbols[names[j]]
+		if a != b {
+			return a < b
+		}
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		fmt.Fprintf(bw, "%s\tequ\t$%04X\n", name, p.Symbols[name])
+	}
+	return bw.Flush()
+}
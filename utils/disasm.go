@@ -45,19 +45,68 @@ func Disassemble(prog []byte) []*Inst {
 
 func Decode(inst uint16) *Inst {
 	switch {
-	case 0x00E0 == (inst & 0x0F0):
+	case 0x00C0 == (inst & 0xFFF0):
+		return &Inst{
+			Inst:     inst,
+			Mnemonic: "SCDOWN",
+			OpCode:   inst & 0xFFF0,
+			EmitStr:  "\t" + "SCDOWN" + "\t" + toHexStr(inst&0x000F),
+		}
+	case 0x00FB == inst:
+		return &Inst{
+			Inst:     inst,
+			Mnemonic: "SCRIGHT",
+			OpCode:   inst,
+			EmitStr:  "\t" + "SCRIGHT",
+		}
+	case 0x00FC == inst:
+		return &Inst{
+			Inst:     inst,
+			Mnemonic: "SCLEFT",
+			OpCode:   inst,
+			EmitStr:  "\t" + "SCLEFT",
+		}
+	case 0x00FD == inst:
+		return &Inst{
+			Inst:     inst,
+			Mnemonic: "EXIT",
+			OpCode:   inst,
+			EmitStr:  "\t" + "EXIT",
+		}
+	case 0x00FE == inst:
+		return &Inst{
+			Inst:     inst,
+			Mnemonic: "LOW",
+			OpCode:   inst,
+			EmitStr:  "\t" + "LOW",
+		}
+	case 0x00FF == inst:
+		return &Inst{
+			Inst:     inst,
+			Mnemonic: "HIGH",
+			OpCode:   inst,
+			EmitStr:  "\t" + "HIGH",
+		}
+	case 0x00D0 == (inst & 0xFFF0):
+		return &Inst{
+			Inst:     inst,
+			Mnemonic: "SCUP",
+			OpCode:   inst & 0xFFF0,
+			EmitStr:  "\t" + "SCUP" + "\t" + toHexStr(inst&0x000F),
+		}
+	case 0x00E0 == inst:
 		return &Inst{
 			Inst:     inst,
 			Mnemonic: "CLS",
 			OpCode:   inst & 0x00FF,
-			EmitStr:  "\t" + "CLS" + "\t\t" + toHexStr(inst&0x00F0),
+			EmitStr:  "\t" + "CLS",
 		}
-	case 0x00EE == (inst & 0x00FF):
+	case 0x00EE == inst:
 		return &Inst{
 			Inst:     inst,
 			Mnemonic: "RTS",
 			OpCode:   inst & 0x00FF,
-			EmitStr:  "\t" + "RTS" + "\t\t" + toHexStr(inst&0x00FF),
+			EmitStr:  "\t" + "RTS",
 		}
 	case 0x1000 == (inst & 0xF000):
 		return &Inst{
@@ -70,7 +119,7 @@ func Decode(inst uint16) *Inst {
 	case 0x2000 == (inst & 0xF000):
 		return &Inst{
 			Inst:     inst,
-			Mnemonic: "JSP",
+			Mnemonic: "JSR",
 			OpCode:   inst & 0xF000,
 			OpAddr:   inst & 0x0FFF,
 			EmitStr:  "\t" + "JSR" + "\t\t" + toHexStr(inst&0x0FFF),
@@ -93,7 +142,7 @@ func Decode(inst uint16) *Inst {
 			OpAddr:   inst & 0x00FF,
 			EmitStr:  "\t" + "SKNE" + "\t" + vxToStr(inst) + ", " + toHexStr(inst&0x00FF),
 		}
-	case 0x5000 == (inst & 0xF000):
+	case 0x5000 == (inst & 0xF00F):
 		return &Inst{
 			Inst:     inst,
 			Mnemonic: "SKEQ",
@@ -102,6 +151,24 @@ func Decode(inst uint16) *Inst {
 			OpCode:   inst & 0xF000,
 			EmitStr:  "\t" + "SKEQ" + "\t" + vxToStr(inst) + ", " + vyToStr(inst),
 		}
+	case 0x5002 == (inst & 0xF00F):
+		return &Inst{
+			Inst:     inst,
+			Mnemonic: "SAVE",
+			Vx:       inst & 0x0F00,
+			Vy:       inst & 0x00F0,
+			OpCode:   inst & 0xF00F,
+			EmitStr:  "\t" + "SAVE" + "\t" + vxToStr(inst) + ", " + vyToStr(inst),
+		}
+	case 0x5003 == (inst & 0xF00F):
+		return &Inst{
+			Inst:     inst,
+			Mnemonic: "RESTORE",
+			Vx:       inst & 0x0F00,
+			Vy:       inst & 0x00F0,
+			OpCode:   inst & 0xF00F,
+			EmitStr:  "\t" + "RESTORE" + "\t" + vxToStr(inst) + ", " + vyToStr(inst),
+		}
 	case 0x6000 == (inst & 0xF000):
 		return &Inst{
 			Inst:     inst,
@@ -180,7 +247,7 @@ func Decode(inst uint16) *Inst {
 			Mnemonic: "SHR",
 			Vx:       inst & 0x0F00,
 			OpCode:   inst & 0xF00F,
-			EmitStr:  "\t" + "SHR" + "\t\t" + vxToStr(inst),
+			EmitStr:  "\t" + "SHR" + "\t\t" + shiftRegsToStr(inst),
 		}
 	case 0x8007 == (inst & 0xF00F):
 		return &Inst{
@@ -197,10 +264,10 @@ func Decode(inst uint16) *Inst {
 			Mnemonic: "SHL",
 			Vx:       inst & 0x0F00,
 			OpCode:   inst & 0xF00F,
-			EmitStr:  "\t" + "SHL" + "\t\t" + vxToStr(inst),
+			EmitStr:  "\t" + "SHL" + "\t\t" + shiftRegsToStr(inst),
 		}
 
-	case 0x9000 == (inst & 0xF000):
+	case 0x9000 == (inst & 0xF00F):
 		return &Inst{
 			Inst:     inst,
 			Mnemonic: "SKNE",
@@ -245,29 +312,58 @@ func Decode(inst uint16) *Inst {
 			Inst:     inst,
 			Mnemonic: "SKPR",
 			OpCode:   inst & 0xF0FF,
-			EmitStr:  "\t" + "SKPR" + "\t" + "KEY " + toHexStr(inst&0x0F00),
+			EmitStr:  "\t" + "SKPR" + "\t" + vxToStr(inst),
 		}
 	case 0xE0A1 == (inst & 0xF0FF):
 		return &Inst{
 			Inst:     inst,
 			Mnemonic: "SKUP",
 			OpCode:   inst & 0xF0FF,
-			EmitStr:  "\t" + "SKUP" + "\t" + "KEY " + toHexStr(inst&0x0F00),
+			EmitStr:  "\t" + "SKUP" + "\t" + vxToStr(inst),
+		}
+	case 0xF000 == inst:
+		return &Inst{
+			Inst:     inst,
+			Mnemonic: "LONGI",
+			OpCode:   inst,
+			EmitStr:  "\t" + "LONGI",
+		}
+	case 0xF001 == (inst & 0xF0FF):
+		return &Inst{
+			Inst:     inst,
+			Mnemonic: "PLANE",
+			OpCode:   inst & 0xF0FF,
+			EmitStr:  "\t" + "PLANE" + "\t" + toHexStr((inst&0x0F00)>>8),
+		}
+	case 0xF002 == inst:
+		return &Inst{
+			Inst:     inst,
+			Mnemonic: "AUDIO",
+			OpCode:   inst,
+			EmitStr:  "\t" + "AUDIO",
+		}
+	case 0xF03A == (inst & 0xF0FF):
+		return &Inst{
+			Inst:     inst,
+			Vx:       inst & 0xF0FF,
+			Mnemonic: "PITCH",
+			OpCode:   inst & 0xF0FF,
+			EmitStr:  "\t" + "PITCH" + "\t" + vxToStr(inst),
 		}
-	case 0xF007 == (inst & 0xF00F):
+	case 0xF007 == (inst & 0xF0FF):
 		return &Inst{
 			Inst:     inst,
 			Vx:       inst & 0xF00F,
 			Mnemonic: "MOVDT",
-			OpCode:   inst & 0xF00F,
+			OpCode:   inst & 0xF0FF,
 			EmitStr:  "\t" + "MOVDT" + "\t" + vxToStr(inst),
 		}
-	case 0xF00A == (inst & 0xF00F):
+	case 0xF00A == (inst & 0xF0FF):
 		return &Inst{
 			Inst:     inst,
 			Vx:       inst & 0xF0FF,
 			Mnemonic: "KEYPR",
-			OpCode:   inst & 0xF00F,
+			OpCode:   inst & 0xF0FF,
 			EmitStr:  "\t" + "KEYPR" + "\t" + vxToStr(inst),
 		}
 	case 0xF015 == (inst & 0xF0FF):
@@ -310,11 +406,35 @@ func Decode(inst uint16) *Inst {
 			OpCode:   inst & 0xF0FF,
 			EmitStr:  "\t" + "BCD" + "\t\t" + vxToStr(inst),
 		}
+	case 0xF030 == (inst & 0xF0FF):
+		return &Inst{
+			Inst:     inst,
+			Vx:       inst & 0xF0FF,
+			Mnemonic: "XFONT",
+			OpCode:   inst & 0xF0FF,
+			EmitStr:  "\t" + "XFONT" + "\t" + vxToStr(inst),
+		}
+	case 0xF075 == (inst & 0xF0FF):
+		return &Inst{
+			Inst:     inst,
+			Vx:       inst & 0xF0FF,
+			Mnemonic: "STRPL",
+			OpCode:   inst & 0xF0FF,
+			EmitStr:  "\t" + "STRPL" + "\t" + vxToStr(inst),
+		}
+	case 0xF085 == (inst & 0xF0FF):
+		return &Inst{
+			Inst:     inst,
+			Vx:       inst & 0xF0FF,
+			Mnemonic: "LDRPL",
+			OpCode:   inst & 0xF0FF,
+			EmitStr:  "\t" + "LDRPL" + "\t" + vxToStr(inst),
+		}
 	case 0xF055 == (inst & 0xF0FF):
 		return &Inst{
 			Inst:     inst,
 			Vx:       inst & 0xF0FF,
-			Mnemonic: "STOTE",
+			Mnemonic: "STORE",
 			OpCode:   inst & 0xF0FF,
 			EmitStr:  "\t" + "STORE" + "\t" + vxToStr(inst),
 		}
@@ -342,10 +462,19 @@ func vyToStr(inst uint16) string {
 	return regToStr(uint8((inst & 0x00F0) >> 4))
 }
 
+//shiftRegsToStr the operands of SHR/SHL, Vy is only given when it differs from Vx
+func shiftRegsToStr(inst uint16) string {
+	if (inst&0x0F00)>>8 == (inst&0x00F0)>>4 {
+		return vxToStr(inst)
+	}
+	return vxToStr(inst) + ", " + vyToStr(inst)
+}
+
 func regToStr(reg uint8) string {
-	return fmt.Sprintf("V%x", reg)
+	return fmt.Sprintf("V%X", reg)
 }
 
+//toHexStr formats a value as hex, with the $ prefix understood by the assembler
 func toHexStr(inst uint16) string {
-	return fmt.Sprintf("%x", inst)
+	return fmt.Sprintf("$%X", inst)
 }
@@ -0,0 +1,243 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+//Label prefixes, in order of preference when an address is referenced in more
+//than one way
+var labelKinds = []string{"sub", "lbl", "table", "data"}
+
+//flowDisasm separates a ROM into code and data by following its control flow from
+//the entry point at 0x200
+type flowDisasm struct {
+	rom []byte
+	//insts the size of each instruction found, by address
+	insts map[int]int
+	//code whether each byte of the ROM is part of an instruction
+	code   []bool
+	labels map[int]string
+	queue  []int
+}
+
+//WriteDisassembly disassembles a ROM loaded at 0x200 as source for the assembler.
+//Code is found by following jumps, calls and skips from the entry point, anything
+//unreachable is written as db data with a preview of the pixels of each byte, as
+//it is most likely sprite data. Jump and call targets and addresses loaded into I
+//are given labels.
+func WriteDisassembly(w io.Writer, rom []byte) error {
+	return traceCode(rom).write(w)
+}
+
+//traceCode finds the code in a ROM loaded at 0x200
+func traceCode(rom []byte) *flowDisasm {
+	d := &flowDisasm{
+		rom:    rom,
+		insts:  make(map[int]int),
+		code:   make([]bool, len(rom)),
+		labels: make(map[int]string),
+	}
+	d.trace(progStart)
+	return d
+}
+
+//word reads the instruction at addr
+func (d *flowDisasm) word(addr int) uint16 {
+	i := addr - progStart
+	return uint16(d.rom[i])<<8 | uint16(d.rom[i+1])
+}
+
+//inROM whether n bytes from addr are all within the ROM
+func (d *flowDisasm) inROM(addr, n int) bool {
+	return addr >= progStart && addr+n <= progStart+len(d.rom)
+}
+
+//label names an address, keeping the most preferred kind if already named
+func (d *flowDisasm) label(addr int, kind string) {
+	name := fmt.Sprintf("%s_%03X", kind, addr)
+	if existing, exists := d.labels[addr]; exists && labelRank(existing) <= labelRank(name) {
+		return
+	}
+	d.labels[addr] = name
+}
+
+func labelRank(name string) int {
+	for i, kind := range labelKinds {
+		if strings.HasPrefix(name, kind+"_") {
+			return i
+		}
+	}
+	return len(labelKinds)
+}
+
+//trace follows the control flow from entry, marking every instruction reached
+func (d *flowDisasm) trace(entry int) {
+	d.queue = append(d.queue, entry)
+	for len(d.queue) > 0 {
+		addr := d.queue[len(d.queue)-1]
+		d.queue = d.queue[:len(d.queue)-1]
+		for d.decodeAt(addr) {
+			addr += d.insts[addr]
+		}
+	}
+}
+
+//decodeAt marks the instruction at addr as code, queueing any branch targets.
+//Returns true if execution can continue with the following instruction.
+func (d *flowDisasm) decodeAt(addr int) bool {
+	if _, done := d.insts[addr]; done || !d.inROM(addr, 2) {
+		return false
+	}
+	inst := d.word(addr)
+	size := 2
+	if inst == 0xF000 {
+		size = 4
+	}
+	if !d.inROM(addr, size) || Decode(inst).Mnemonic == "" {
+		return false
+	}
+	//Don't decode instructions overlapping those already found
+	for i := 0; i < size; i++ {
+		if d.code[addr-progStart+i] {
+			return false
+		}
+	}
+	for i := 0; i < size; i++ {
+		d.code[addr-progStart+i] = true
+	}
+	d.insts[addr] = size
+
+	nnn := int(inst & 0x0FFF)
+	switch {
+	case inst == 0x00EE || inst == 0x00FD:
+		return false
+	case inst&0xF000 == 0x1000:
+		d.label(nnn, "lbl")
+		d.queue = append(d.queue, nnn)
+		return false
+	case inst&0xF000 == 0xB000:
+		//A jump table, only its first entry can be found
+		d.label(nnn, "table")
+		d.queue = append(d.queue, nnn)
+		return false
+	case inst&0xF000 == 0x2000:
+		d.label(nnn, "sub")
+		d.queue = append(d.queue, nnn)
+	case inst&0xF000 == 0xA000:
+		d.label(nnn, "data")
+	case inst == 0xF000:
+		d.label(int(d.word(addr+2)), "data")
+	case isSkip(inst):
+		next := addr + 2
+		if d.inROM(next, 2) && d.word(next) == 0xF000 {
+			d.queue = append(d.queue, next+4)
+		} else {
+			d.queue = append(d.queue, next+2)
+		}
+	}
+	return true
+}
+
+//isSkip whether the instruction conditionally skips the next one
+func isSkip(inst uint16) bool {
+	switch inst & 0xF000 {
+	case 0x3000, 0x4000:
+		return true
+	case 0x5000, 0x9000:
+		return inst&0x000F == 0
+	case 0xE000:
+		return inst&0x00FF == 0x9E || inst&0x00FF == 0xA1
+	}
+	return false
+}
+
+//addrStr an address operand, by label where there is one
+func (d *flowDisasm) addrStr(addr int) string {
+	if name, exists := d.labels[addr]; exists {
+		return name
+	}
+	return toHexStr(uint16(addr))
+}
+
+//instStr the source for the instruction at addr, using labels for addresses
+func (d *flowDisasm) instStr(addr int) string {
+	inst := d.word(addr)
+	nnn := int(inst & 0x0FFF)
+	switch {
+	case inst&0xF000 == 0x1000:
+		return "\tJMP\t\t" + d.addrStr(nnn)
+	case inst&0xF000 == 0x2000:
+		return "\tJSR\t\t" + d.addrStr(nnn)
+	case inst&0xF000 == 0xB000:
+		return "\tJMP\t\tV0 + " + d.addrStr(nnn)
+	case inst&0xF000 == 0xA000:
+		return "\tMOVE\tI, " + d.addrStr(nnn)
+	case inst == 0xF000:
+		return "\tLONGI\t" + d.addrStr(int(d.word(addr+2)))
+	}
+	return Decode(inst).EmitStr
+}
+
+//placeable whether a label can be written on a line of its own, rather than
+//having to be defined with equ
+func (d *flowDisasm) placeable(addr int) bool {
+	if !d.inROM(addr, 1) {
+		return false
+	}
+	_, isInst := d.insts[addr]
+	return isInst || !d.code[addr-progStart]
+}
+
+func (d *flowDisasm) write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "; Disassembled by tracing control flow from $200, unreachable bytes are")
+	fmt.Fprintln(bw, "; written as data")
+
+	addrs := make([]int, 0, len(d.labels))
+	for addr := range d.labels {
+		addrs = append(addrs, addr)
+	}
+	sort.Ints(addrs)
+	equs := false
+	for _, addr := range addrs {
+		if !d.placeable(addr) {
+			fmt.Fprintf(bw, "%s\tequ\t%s\n", d.labels[addr], toHexStr(uint16(addr)))
+			equs = true
+		}
+	}
+	if equs {
+		fmt.Fprintln(bw)
+	}
+
+	for addr := progStart; addr < progStart+len(d.rom); {
+		if name, exists := d.labels[addr]; exists {
+			fmt.Fprintf(bw, "%s:\n", name)
+		}
+		if size, exists := d.insts[addr]; exists {
+			fmt.Fprintln(bw, d.instStr(addr))
+			addr += size
+			continue
+		}
+		b := d.rom[addr-progStart]
+		fmt.Fprintf(bw, "\tdb\t%%%08b\t; %s\n", b, pixelPreview(b))
+		addr++
+	}
+	return bw.Flush()
+}
+
+//pixelPreview draws the 8 pixels of a byte of sprite data
+func pixelPreview(b byte) string {
+	var s strings.Builder
+	for mask := byte(0x80); mask > 0; mask >>= 1 {
+		if b&mask != 0 {
+			s.WriteByte('#')
+		} else {
+			s.WriteByte('.')
+		}
+	}
+	return s.String()
+}
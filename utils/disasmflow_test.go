@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDisassemblyReassembles(t *testing.T) {
+	paths, _ := filepath.Glob("../games/*")
+	if len(paths) == 0 {
+		t.Skip("no ROMs in ../games")
+	}
+	for _, path := range paths {
+		rom, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var src bytes.Buffer
+		if err := WriteDisassembly(&src, rom); err != nil {
+			t.Fatal(err)
+		}
+		prog, err := Assemble(&src, filepath.Base(path)+".asm")
+		if err == nil && bytes.Equal(prog.ROM, rom) {
+			t.Log(filepath.Base(path), " reassembles - Test Passed")
+		} else {
+			msg := fmt.Sprintf("%s did not reassemble to the same ROM, error %v", path, err)
+			t.Error(msg)
+		}
+	}
+}
+
+func TestDisassemblySeparatesData(t *testing.T) {
+	rom := []byte{
+		0xA2, 0x0A, //MOVE I, sprite
+		0x22, 0x08, //JSR sub
+		0x12, 0x04, //JMP to itself
+		0xFF, 0xFF, //unreachable
+		0x00, 0xEE, //sub: RTS
+		0xA0, 0x40, //sprite
+	}
+	var src bytes.Buffer
+	WriteDisassembly(&src, rom)
+	out := src.String()
+
+	expected := []string{
+		"\tMOVE\tI, data_20A\n",
+		"\tJSR\t\tsub_208\n",
+		"lbl_204:\n\tJMP\t\tlbl_204\n",
+		"\tdb\t%11111111\t; ########\n",
+		"sub_208:\n\tRTS\n",
+		"data_20A:\n\tdb\t%10100000\t; #.#.....\n\tdb\t%01000000\t; .#......\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			msg := fmt.Sprintf("Expected disassembly to contain %q:\n%s", e, out)
+			t.Error(msg)
+			return
+		}
+	}
+	t.Log("Code and data separated - Test Passed")
+}
+
+func TestDecodeReassembles(t *testing.T) {
+	mismatches := 0
+	for op := 0; op <= 0xFFFF; op++ {
+		inst := Decode(uint16(op))
+		if inst.Mnemonic == "" {
+			//Invalid, so emitted as data
+			continue
+		}
+		src := inst.EmitStr
+		if inst.Mnemonic == "LONGI" {
+			//The address follows as the next word
+			src += "\t$0000"
+		}
+		var rom []byte
+		prog, err := Assemble(strings.NewReader(src+"\n"), "op.asm")
+		if err == nil {
+			rom = prog.ROM
+		}
+		if len(rom) < 2 || rom[0] != uint8(op>>8) || rom[1] != uint8(op) {
+			if mismatches++; mismatches <= 5 {
+				t.Errorf("%04X decoded as %q, reassembled to % X, error %v", op, inst.EmitStr, rom, err)
+			}
+		}
+	}
+	if mismatches == 0 {
+		t.Log("Every decoded opcode reassembles to itself - Test Passed")
+	} else {
+		t.Error(mismatches, " opcodes didn't reassemble")
+	}
+}
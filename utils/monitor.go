@@ -1,12 +1,16 @@
 package utils
 
-type MachineState struct {
-}
+import "sort"
 
 type MachineMonitor struct {
 	active      bool
-	cmdRunStep  bool
+	runSteps    int
+	resuming    bool
 	breakPoints map[uint16]bool
+
+	watches   []Watchpoint
+	nextWatch int
+	hits      []WatchHit
 }
 
 func NewMachineMonitor() *MachineMonitor {
@@ -21,24 +25,45 @@ func (mm *MachineMonitor) Deactivate() {
 	mm.active = false
 }
 
+//Continue deactivates the monitor, running on past a breakpoint at the current
+//address rather than stopping on it again straight away.
+func (mm *MachineMonitor) Continue() {
+	mm.active = false
+	mm.resuming = true
+}
+
 func (mm *MachineMonitor) IsActive() bool {
 	return mm.active
 }
 
+//IsResuming true until the first instruction after a Continue has been executed
+func (mm *MachineMonitor) IsResuming() bool {
+	return mm.resuming
+}
+
 func (mm *MachineMonitor) IsRunStep() bool {
-	return mm.cmdRunStep
+	return mm.runSteps > 0
 }
 
 func (mm *MachineMonitor) SetRunStep() {
-	mm.cmdRunStep = true
+	mm.SetRunSteps(1)
+}
+
+//SetRunSteps allows n instructions to be executed while the monitor is active
+func (mm *MachineMonitor) SetRunSteps(n int) {
+	mm.runSteps = n
 }
 
 func (mm *MachineMonitor) ClearRunStep() {
-	mm.cmdRunStep = false
+	mm.runSteps = 0
 }
 
+//Reset is called after each instruction executed, counting down the run steps
 func (mm *MachineMonitor) Reset() {
-	mm.cmdRunStep = false
+	if mm.runSteps > 0 {
+		mm.runSteps--
+	}
+	mm.resuming = false
 }
 
 func (mm *MachineMonitor) SetBP(address uint16) {
@@ -46,12 +71,19 @@ func (mm *MachineMonitor) SetBP(address uint16) {
 }
 
 func (mm *MachineMonitor) ClrBP(address uint16) {
-	mm.breakPoints[address] = false
+	delete(mm.breakPoints, address)
 }
 
 func (mm *MachineMonitor) IsBP(address uint16) bool {
-	//	println("-----IsBP----")
-	//	println(address)
-	//	println(mm.breakPoints[address])
 	return mm.breakPoints[address]
 }
+
+//BPs get the breakpoint addresses in ascending order
+func (mm *MachineMonitor) BPs() []uint16 {
+	bps := make([]uint16, 0, len(mm.breakPoints))
+	for address := range mm.breakPoints {
+		bps = append(bps, address)
+	}
+	sort.Slice(bps, func(i, j int) bool { return bps[i] < bps[j] })
+	return bps
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+//movieMagic identifies a movie file
+var movieMagic = [4]byte{'C', '8', 'M', 'V'}
+
+//MovieVersion the current movie format version, bump whenever the format changes
+const MovieVersion uint16 = 2
+
+//ErrNotMovie returned when decoding something that isn't a movie
+var ErrNotMovie = errors.New("not a movie")
+
+//MovieHeader the settings a movie was recorded with, needed to reproduce it
+type MovieHeader struct {
+	//Seed the random number generator seed
+	Seed int64
+	//Quirks bit set, as defined by the core
+	Quirks uint8
+	//CyclesPerFrame the instructions per frame at the start of the recording
+	CyclesPerFrame uint16
+	//ROM the SHA-1 of the ROM the movie was recorded with
+	ROM [20]byte
+}
+
+//MovieFrame the input handled at the end of a frame, seen by the frames that follow
+type MovieFrame struct {
+	//Keys the pressed keys as a bit set, bit n set for key n
+	Keys uint16
+	//CyclesPerFrame the instructions per frame for the frames that follow, changed
+	//by the speed hotkeys
+	CyclesPerFrame uint16
+}
+
+//Movie a recording of the input to a session, which can be played back to
+//reproduce it exactly. The VMem hash of the final frame checks the playback.
+type Movie struct {
+	MovieHeader
+	Frames   []MovieFrame
+	VMemHash [32]byte
+}
+
+//Encode writes the movie to w. Following the header, each frame is written as
+//its keys and speed.
+func (m *Movie) Encode(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, v := range []interface{}{movieMagic, MovieVersion, m.MovieHeader, uint32(len(m.Frames)), m.Frames} {
+		if err := binary.Write(bw, binary.BigEndian, v); err != nil {
+			return err
+		}
+	}
+	bw.Write(m.VMemHash[:])
+	return bw.Flush()
+}
+
+//Decode reads a movie written by Encode, rejecting other format versions
+func (m *Movie) Decode(r io.Reader) error {
+	br := bufio.NewReader(r)
+	var magic [4]byte
+	if err := binary.Read(br, binary.BigEndian, &magic); err != nil {
+		return err
+	}
+	if magic != movieMagic {
+		return ErrNotMovie
+	}
+
+	var version uint16
+	if err := binary.Read(br, binary.BigEndian, &version); err != nil {
+		return err
+	}
+	if version != MovieVersion {
+		return fmt.Errorf("unsupported movie version %d, expected %d", version, MovieVersion)
+	}
+
+	var count uint32
+	if err := binary.Read(br, binary.BigEndian, &m.MovieHeader); err != nil {
+		return err
+	}
+	if err := binary.Read(br, binary.BigEndian, &count); err != nil {
+		return err
+	}
+
+	//Read a frame at a time rather than allocating for the count up front, so a
+	//corrupt count fails at the end of the file
+	m.Frames = nil
+	for i := uint32(0); i < count; i++ {
+		var f MovieFrame
+		if err := binary.Read(br, binary.BigEndian, &f); err != nil {
+			return err
+		}
+		m.Frames = append(m.Frames, f)
+	}
+	_, err := io.ReadFull(br, m.VMemHash[:])
+	return err
+}
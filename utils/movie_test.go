@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestMovieEncoding(t *testing.T) {
+	m := &Movie{
+		MovieHeader: MovieHeader{Seed: -42, Quirks: 0x15, CyclesPerFrame: 8, ROM: [20]byte{1, 2, 3}},
+		Frames: []MovieFrame{
+			{Keys: 0x0010, CyclesPerFrame: 8},
+			{Keys: 0x8001, CyclesPerFrame: 10},
+		},
+		VMemHash: [32]byte{31: 0xFF},
+	}
+	var buf bytes.Buffer
+	if err := m.Encode(&buf); err != nil {
+		t.Fatal("Encode failed: ", err)
+	}
+
+	decoded := &Movie{}
+	if err := decoded.Decode(bytes.NewReader(buf.Bytes())); err == nil && reflect.DeepEqual(m, decoded) {
+		t.Log("Movie decoded as encoded - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Expected %+v, actual %+v, error %v", m, decoded, err)
+		t.Error(msg)
+	}
+
+	if err := decoded.Decode(bytes.NewReader(buf.Bytes()[:buf.Len()-1])); err != nil {
+		t.Log("Truncated movie rejected - Test Passed")
+	} else {
+		t.Error("Expected an error decoding a truncated movie")
+	}
+	if err := decoded.Decode(bytes.NewReader([]byte("C8ST\x00\x02"))); err == ErrNotMovie {
+		t.Log("Save state rejected as a movie - Test Passed")
+	} else {
+		t.Error("Expected ErrNotMovie, actual ", err)
+	}
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+)
+
+//Chip-8 variants, as reported by RomInfo
+const (
+	ExtChip8     = "CHIP-8"
+	ExtSuperChip = "SUPER-CHIP"
+	ExtXOChip    = "XO-CHIP"
+)
+
+//RomInfo a summary of a ROM file
+type RomInfo struct {
+	Size int
+	//CodeSize the number of bytes reachable as instructions from 0x200, the rest
+	//of the ROM is data
+	CodeSize int
+	//Extension the variant needed to run the ROM, based on the instructions it uses
+	Extension string
+	SHA1      string
+}
+
+//InspectROM summarises a ROM loaded at 0x200
+func InspectROM(rom []byte) *RomInfo {
+	d := traceCode(rom)
+	sum := sha1.Sum(rom)
+	info := &RomInfo{
+		Size:      len(rom),
+		Extension: ExtChip8,
+		SHA1:      hex.EncodeToString(sum[:]),
+	}
+	//XO-CHIP ROMs can fill the whole 64KiB address space
+	if len(rom) > 0x1000-progStart {
+		info.Extension = ExtXOChip
+	}
+	for addr, size := range d.insts {
+		info.CodeSize += size
+		if ext := instExtension(d.word(addr)); ext == ExtXOChip || info.Extension == ExtChip8 {
+			info.Extension = ext
+		}
+	}
+	return info
+}
+
+//instExtension the variant that introduced an instruction
+func instExtension(inst uint16) string {
+	switch {
+	case inst == 0xF000 || inst == 0xF002 || inst&0xFFF0 == 0x00D0,
+		inst&0xF00F == 0x5002 || inst&0xF00F == 0x5003,
+		inst&0xF0FF == 0xF001 || inst&0xF0FF == 0xF03A:
+		return ExtXOChip
+	case inst >= 0x00FB && inst <= 0x00FF, inst&0xFFF0 == 0x00C0,
+		inst&0xF00F == 0xD000,
+		inst&0xF0FF == 0xF030 || inst&0xF0FF == 0xF075 || inst&0xF0FF == 0xF085:
+		return ExtSuperChip
+	}
+	return ExtChip8
+}
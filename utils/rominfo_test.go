@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestInspectROM(t *testing.T) {
+	tests := []struct {
+		rom       []byte
+		extension string
+	}{
+		{[]byte{0x00, 0xE0, 0x12, 0x02}, ExtChip8},
+		{[]byte{0x00, 0xFF, 0x12, 0x02}, ExtSuperChip},
+		{[]byte{0x00, 0xFF, 0xF0, 0x00, 0x02, 0x00, 0x12, 0x06}, ExtXOChip},
+		//Unreachable bytes don't count towards the extension
+		{[]byte{0x12, 0x00, 0xF0, 0x00}, ExtChip8},
+	}
+	for _, test := range tests {
+		info := InspectROM(test.rom)
+		if info.Extension == test.extension && info.Size == len(test.rom) {
+			t.Log(test.extension + " detected - Test Passed")
+		} else {
+			msg := fmt.Sprintf("% X: expected %s, actual %+v", test.rom, test.extension, info)
+			t.Error(msg)
+		}
+	}
+
+	info := InspectROM([]byte{0x12, 0x00, 0xF0, 0x00})
+	if info.CodeSize == 2 && info.SHA1 == "1f32f02dfbe0b65acba6a9ed05045f3f60bf410d" {
+		t.Log("Code size and hash - Test Passed")
+	} else {
+		msg := fmt.Sprintf("Unexpected code size or hash %+v", info)
+		t.Error(msg)
+	}
+}
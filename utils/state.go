@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+//stateMagic identifies a save state file
+var stateMagic = [4]byte{'C', '8', 'S', 'T'}
+
+//StateVersion the current save state format version, bump whenever MachineState changes
+const StateVersion uint16 = 3
+
+//ErrNotSaveState returned when decoding something that isn't a save state
+var ErrNotSaveState = errors.New("not a save state")
+
+//MachineState a snapshot of the full machine state. Every field is fixed size so the
+//state can be written as a single binary record following the header.
+type MachineState struct {
+	Memory [0x10000]uint8
+	V      [16]uint8
+	I      uint16
+	Pc     uint16
+	S      [16]uint16
+	Sp     uint8
+
+	DelayTimer uint8
+	SoundTimer uint8
+	Cycles     uint64
+	Keys       [16]uint8
+
+	//An FX0A wait for a key, see core.KeyWait
+	KeyWait     bool
+	KeyWaitReg  uint8
+	KeyWaitHeld bool
+	KeyWaitKey  uint8
+
+	VMem   [64][128]uint8
+	HiRes  bool
+	Halted bool
+	RPL    [16]uint8
+	Planes uint8
+
+	AudioPattern  [16]uint8
+	Pitch         uint8
+	PatternLoaded bool
+
+	//Quirks bit set, as defined by the core
+	Quirks uint8
+}
+
+//Encode writes the state to w, preceded by a header holding the format version
+func (s *MachineState) Encode(w io.Writer) error {
+	if err := binary.Write(w, binary.BigEndian, stateMagic); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, StateVersion); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.BigEndian, s)
+}
+
+//Decode reads a state written by Encode, rejecting other format versions
+func (s *MachineState) Decode(r io.Reader) error {
+	var magic [4]byte
+	if err := binary.Read(r, binary.BigEndian, &magic); err != nil {
+		return err
+	}
+	if magic != stateMagic {
+		return ErrNotSaveState
+	}
+
+	var version uint16
+	if err := binary.Read(r, binary.BigEndian, &version); err != nil {
+		return err
+	}
+	if version != StateVersion {
+		return fmt.Errorf("unsupported save state version %d, expected %d", version, StateVersion)
+	}
+	return binary.Read(r, binary.BigEndian, s)
+}
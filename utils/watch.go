@@ -0,0 +1,167 @@
+package utils
+
+import "fmt"
+
+//WatchKind the machine state a watchpoint is placed on
+type WatchKind uint8
+
+//Watchpoint kinds
+const (
+	WatchMem WatchKind = iota
+	WatchV
+	WatchI
+	WatchDT
+	WatchST
+)
+
+var watchKindNames = map[WatchKind]string{
+	WatchMem: "mem",
+	WatchV:   "v",
+	WatchI:   "i",
+	WatchDT:  "dt",
+	WatchST:  "st",
+}
+
+func (k WatchKind) String() string {
+	return watchKindNames[k]
+}
+
+//WatchAccess the type of access a watchpoint triggers on, read, write or both
+type WatchAccess uint8
+
+//Watchpoint access types
+const (
+	WatchRead WatchAccess = 1 << iota
+	WatchWrite
+	WatchReadWrite = WatchRead | WatchWrite
+)
+
+func (a WatchAccess) String() string {
+	switch a {
+	case WatchRead:
+		return "read"
+	case WatchWrite:
+		return "write"
+	case WatchReadWrite:
+		return "rw"
+	}
+	return fmt.Sprintf("WatchAccess(%d)", uint8(a))
+}
+
+//Watchpoint stops the machine when an instruction accesses part of its state.
+//Start and End give the inclusive range of memory addresses or V registers
+//watched, they are unused for I and the timers.
+type Watchpoint struct {
+	ID     int
+	Kind   WatchKind
+	Start  uint16
+	End    uint16
+	Access WatchAccess
+}
+
+//matches whether the watchpoint covers the given access
+func (w *Watchpoint) matches(kind WatchKind, access WatchAccess, index uint16) bool {
+	if w.Kind != kind || w.Access&access == 0 {
+		return false
+	}
+	if kind == WatchMem || kind == WatchV {
+		return index >= w.Start && index <= w.End
+	}
+	return true
+}
+
+func (w *Watchpoint) String() string {
+	switch w.Kind {
+	case WatchMem:
+		if w.Start == w.End {
+			return fmt.Sprintf("%s mem 0x%03X", w.Access, w.Start)
+		}
+		return fmt.Sprintf("%s mem 0x%03X-0x%03X", w.Access, w.Start, w.End)
+	case WatchV:
+		if w.Start == w.End {
+			return fmt.Sprintf("%s V%X", w.Access, w.Start)
+		}
+		return fmt.Sprintf("%s V%X-V%X", w.Access, w.Start, w.End)
+	}
+	return fmt.Sprintf("%s %s", w.Access, w.Kind)
+}
+
+//WatchHit records an access that triggered a watchpoint, along with the
+//instruction that made it. Old and New are equal for reads.
+type WatchHit struct {
+	Watch  Watchpoint
+	Access WatchAccess
+	Index  uint16
+	Old    uint16
+	New    uint16
+	Pc     uint16
+	Opcode uint16
+}
+
+func (h *WatchHit) String() string {
+	var target string
+	switch h.Watch.Kind {
+	case WatchMem:
+		target = fmt.Sprintf("mem 0x%03X", h.Index)
+	case WatchV:
+		target = fmt.Sprintf("V%X", h.Index)
+	default:
+		target = h.Watch.Kind.String()
+	}
+	if h.Access == WatchRead {
+		return fmt.Sprintf("Watchpoint %d: read %s = 0x%02X by %04X at 0x%03X",
+			h.Watch.ID, target, h.Old, h.Opcode, h.Pc)
+	}
+	return fmt.Sprintf("Watchpoint %d: write %s 0x%02X -> 0x%02X by %04X at 0x%03X",
+		h.Watch.ID, target, h.Old, h.New, h.Opcode, h.Pc)
+}
+
+//AddWatch adds a watchpoint, returning its ID
+func (mm *MachineMonitor) AddWatch(w Watchpoint) int {
+	mm.nextWatch++
+	w.ID = mm.nextWatch
+	mm.watches = append(mm.watches, w)
+	return w.ID
+}
+
+//DelWatch removes the watchpoint with the given ID, returning false if there is none
+func (mm *MachineMonitor) DelWatch(id int) bool {
+	for i, w := range mm.watches {
+		if w.ID == id {
+			mm.watches = append(mm.watches[:i], mm.watches[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+//Watches get the watchpoints in the order they were added
+func (mm *MachineMonitor) Watches() []Watchpoint {
+	return append([]Watchpoint(nil), mm.watches...)
+}
+
+//HasWatches whether any watchpoints are set, allowing the machine to skip
+//building the details of an access when there are none
+func (mm *MachineMonitor) HasWatches() bool {
+	return len(mm.watches) > 0
+}
+
+//CheckWatch checks an access against the watchpoints. If any match, the hit is
+//recorded and the monitor activated, stopping the machine once the current
+//instruction completes.
+func (mm *MachineMonitor) CheckWatch(kind WatchKind, access WatchAccess, index, old, new, pc, opcode uint16) {
+	for _, w := range mm.watches {
+		if w.matches(kind, access, index) {
+			mm.hits = append(mm.hits, WatchHit{Watch: w, Access: access, Index: index, Old: old, New: new, Pc: pc, Opcode: opcode})
+			mm.runSteps = 0
+			mm.active = true
+		}
+	}
+}
+
+//TakeHits get the watchpoint hits since the last call, clearing them
+func (mm *MachineMonitor) TakeHits() []WatchHit {
+	hits := mm.hits
+	mm.hits = nil
+	return hits
+}
@@ -1,11 +1,13 @@
 package view
 
 import (
+	"chip8emu/audio"
+	"chip8emu/backend"
+	"chip8emu/capture"
 	"chip8emu/core"
+	"chip8emu/keymap"
 	"chip8emu/opts"
 	"runtime"
-	"sync"
-	"time"
 
 	"gopkg.in/veandco/go-sdl2.v0/sdl"
 )
@@ -17,48 +19,57 @@ const (
 	ScreenHeight = 32 * PixelHeight
 )
 
-var keyboard2Chip8 = map[sdl.Keycode]uint8{
-	sdl.K_1: core.ChipKey1,
-	sdl.K_2: core.ChipKey2,
-	sdl.K_3: core.ChipKey3,
-	sdl.K_4: core.ChipKeyC,
-	sdl.K_q: core.ChipKey4,
-	sdl.K_w: core.ChipKey5,
-	sdl.K_e: core.ChipKey6,
-	sdl.K_r: core.ChipKeyD,
-	sdl.K_a: core.ChipKey7,
-	sdl.K_s: core.ChipKey8,
-	sdl.K_d: core.ChipKey9,
-	sdl.K_f: core.ChipKeyE,
-	sdl.K_z: core.ChipKeyA,
-	sdl.K_x: core.ChipKey0,
-	sdl.K_c: core.ChipKeyB,
-	sdl.K_v: core.ChipKeyF,
-}
+//Colours used for the XO-CHIP plane combinations, index 0 (background) is
+//overridden by the background colour option.
+var palette = capture.DefaultPalette
 
 type SDLDisplayRenderer struct {
 	Alive     bool
 	SdlWindow *sdl.Window
 	cpu       *core.Chip8
-	vmem      []uint8
 	ExitChan  chan<- int
-	WaitGroup *sync.WaitGroup
 	bgColour  uint32
+	palette   [4]uint32
 	xSize     int
 	ySize     int
 	xDisplay  int
 	yDisplay  int
 	pixel     *sdl.Rect
+	input     *SDLInput
+	tone      audio.Tone
+}
+
+func init() {
+	backend.Register("sdl", func(cpu *core.Chip8, opts *opts.Opts) (backend.Backend, error) {
+		return NewSDLDisplayRenderer(cpu, opts)
+	})
 }
 
-func NewSDLDisplayRenderer(cpu *core.Chip8, wg *sync.WaitGroup, opts *opts.Opts) *SDLDisplayRenderer {
+//NewSDLDisplayRenderer creates the SDL window and attaches it to the machine along
+//with SDL input and audio, the machine then drives rendering and input from its
+//FDE loop. Must be called from the
+//goroutine that runs the machine.
+func NewSDLDisplayRenderer(cpu *core.Chip8, opts *opts.Opts) (*SDLDisplayRenderer, error) {
+	tone, err := audio.NewTone(opts)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := keymap.New(opts)
+	if err != nil {
+		return nil, err
+	}
+	bindings, err := newKeyBindings(keys)
+	if err != nil {
+		return nil, err
+	}
 	runtime.LockOSThread()
-	renderer := &SDLDisplayRenderer{}
-	renderer.WaitGroup = wg
+	renderer := &SDLDisplayRenderer{tone: tone}
 
 	renderer.Alive = true
-	renderer.WaitGroup.Add(1)
 	renderer.bgColour = opts.BgColour
+	renderer.palette = palette
+	renderer.palette[0] = opts.BgColour
+	renderer.input = &SDLInput{display: renderer, bindings: bindings, pads: newGamepads(keys), romFile: opts.File, stateDir: opts.StateDir, capture: capture.NewOptions(opts)}
 
 	if renderer.xSize = opts.XSize; renderer.xSize == 0 {
 		renderer.xSize = PixelWidth
@@ -80,50 +91,49 @@ func NewSDLDisplayRenderer(cpu *core.Chip8, wg *sync.WaitGroup, opts *opts.Opts)
 
 	renderer.Init(cpu)
 
-	go renderer.Render()
-	return renderer
+	return renderer, nil
 }
 
 func (r *SDLDisplayRenderer) Init(cpu *core.Chip8) {
 
 	r.cpu = cpu
 	r.cpu.DisplayHandler = r
+	r.input.cpu = cpu
+	r.input.pads.cpu = cpu
+	r.cpu.InputHandler = r.input
 	r.InitDisplay()
-	r.vmem = cpu.Memory[3840:]
+	r.cpu.AudioHandler = NewSDLAudio(cpu, r.tone)
+	r.cpu.Clock = SDLClock{}
 
 }
 
+//Render draws the current contents of video memory, called once per frame
 func (r *SDLDisplayRenderer) Render() {
-	refresh := time.NewTicker(time.Second / 50)
-
-	for r.Alive {
-
-		select {
-		case <-refresh.C:
-			surface, _ := r.SdlWindow.GetSurface()
-
-			rect := sdl.Rect{X: 0, Y: 0, W: int32(r.xDisplay), H: int32(r.yDisplay)}
-			surface.FillRect(&rect, r.bgColour) //0xff00aa00)
-
-			//TODO: Do some timing regarding creating a new rectangle for every pixel vs
-			//one or a pool of rectangles reused.
+	surface, _ := r.SdlWindow.GetSurface()
 
-			for y, v1 := range r.cpu.VMem {
-				for x, v2 := range v1 {
-					if v2 > 0 {
-						r.pixel.X = int32(x * int(r.xSize))
-						r.pixel.Y = int32(y * int(r.ySize))
-						surface.FillRect(r.pixel, uint32(0xffffffff))
-					}
-				}
+	rect := sdl.Rect{X: 0, Y: 0, W: int32(r.xDisplay), H: int32(r.yDisplay)}
+	surface.FillRect(&rect, r.bgColour) //0xff00aa00)
+
+	//TODO: Do some timing regarding creating a new rectangle for every pixel vs
+	//one or a pool of rectangles reused.
+
+	//High resolution pixels are drawn at half size, keeping the window size fixed.
+	//Each pixel's edges are scaled separately, so with an odd pixel size they
+	//alternate between sizes rounded down and up and still cover the window.
+	width, height := r.cpu.ScreenWidth(), r.cpu.ScreenHeight()
+	for y := 0; y < height; y++ {
+		r.pixel.Y = int32(y * r.yDisplay / height)
+		r.pixel.H = int32((y+1)*r.yDisplay/height) - r.pixel.Y
+		for x := 0; x < width; x++ {
+			if v := r.cpu.VMem[y][x]; v > 0 {
+				r.pixel.X = int32(x * r.xDisplay / width)
+				r.pixel.W = int32((x+1)*r.xDisplay/width) - r.pixel.X
+				surface.FillRect(r.pixel, r.palette[v&core.AllPlanes])
 			}
-
-			r.SdlWindow.UpdateSurface()
-
 		}
 	}
 
-	r.WaitGroup.Done()
+	r.SdlWindow.UpdateSurface()
 }
 
 func (r *SDLDisplayRenderer) InitDisplay() {
@@ -147,9 +157,12 @@ func (r *SDLDisplayRenderer) InitDisplay() {
 
 }
 
+//Shutdown closes the window, once the machine has stopped
 func (r *SDLDisplayRenderer) Shutdown() {
 	r.Alive = false
-	r.WaitGroup.Wait()
+	r.input.stopRecording()
+	closeSDLAudio()
+	r.input.pads.closeAll()
 	r.SdlWindow.Destroy()
 	sdl.Quit()
 
@@ -158,70 +171,3 @@ func (r *SDLDisplayRenderer) Shutdown() {
 func (r *SDLDisplayRenderer) IsAlive() bool {
 	return r.Alive
 }
-
-func (r *SDLDisplayRenderer) WaitForInput() uint8 {
-	event := sdl.WaitEvent()
-
-	switch e := event.(type) {
-	case *sdl.KeyDownEvent:
-		return r.handleAwaitKeyPress(*e)
-	}
-
-	return 0
-}
-
-func (r *SDLDisplayRenderer) HandleInput() {
-
-	event := sdl.PollEvent()
-
-	if event != nil {
-		r.handleKeyPress(event)
-	}
-
-}
-
-func (r *SDLDisplayRenderer) handleAwaitKeyPress(event sdl.Event) uint8 {
-	chipKey := keyboard2Chip8[event.(sdl.KeyDownEvent).Keysym.Sym]
-	return chipKey
-}
-
-func (r *SDLDisplayRenderer) handleKeyPress(event sdl.Event) {
-	r.doKeyPress(event)
-}
-
-func (r *SDLDisplayRenderer) doKeyPress(event sdl.Event) {
-
-	switch e := event.(type) {
-	case *sdl.KeyDownEvent:
-		chipKey, exists := keyboard2Chip8[e.Keysym.Sym]
-		if exists {
-			r.cpu.SetKey(chipKey)
-		}
-
-		if e.Keysym.Sym == sdl.K_F1 {
-			print("Activate")
-			r.cpu.MM.Activate()
-		}
-
-		if e.Keysym.Sym == sdl.K_F2 {
-			print("Deactivate")
-			r.cpu.MM.Deactivate()
-		}
-
-		if e.Keysym.Sym == sdl.K_F3 {
-			print("Run Step")
-			r.cpu.MM.SetRunStep()
-		}
-
-		break
-	case *sdl.KeyUpEvent:
-		chipKey, exists := keyboard2Chip8[e.Keysym.Sym]
-		if exists {
-			r.cpu.ClrKey(chipKey)
-		}
-		break
-	case *sdl.QuitEvent:
-		r.Shutdown()
-	}
-
-}
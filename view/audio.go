@@ -0,0 +1,66 @@
+package view
+
+// typedef unsigned char Uint8;
+// void Chip8AudioCallback(void *userdata, Uint8 *stream, int len);
+import "C"
+import (
+	"chip8emu/audio"
+	"chip8emu/core"
+	"time"
+	"unsafe"
+
+	"gopkg.in/veandco/go-sdl2.v0/sdl"
+)
+
+//audioSamples the size of the SDL audio buffer in samples, about 12ms, short
+//enough for the beep to follow the 60Hz sound timer closely
+const audioSamples = 512
+
+//generator the beep played by the audio callback, which can't be passed Go
+//pointers
+var generator *audio.Generator
+
+//NewSDLAudio opens the SDL audio device, which plays the generator's output for
+//as long as the machine runs. The generator is silent between beeps.
+func NewSDLAudio(cpu *core.Chip8, tone audio.Tone) *audio.Output {
+	generator = audio.NewGenerator(tone)
+	desired := &sdl.AudioSpec{}
+	desired.Freq = audio.SampleRate
+	desired.Format = sdl.AUDIO_S16SYS
+	desired.Channels = 1
+	desired.Samples = audioSamples
+	desired.Callback = sdl.AudioCallback(C.Chip8AudioCallback)
+	if err := sdl.OpenAudio(desired, nil); err == nil {
+		sdl.PauseAudio(false)
+	}
+	return audio.NewOutput(cpu, generator)
+}
+
+//closeSDLAudio closes the SDL audio device
+func closeSDLAudio() {
+	sdl.CloseAudio()
+	generator = nil
+}
+
+//SDLClock paces the machine using sdl.Delay
+type SDLClock struct{}
+
+//Sleep delays for the given duration, at millisecond granularity
+func (SDLClock) Sleep(d time.Duration) {
+	sdl.Delay(uint32(d / time.Millisecond))
+}
+
+//Chip8AudioCallback fills the SDL audio buffer from the generator, called on the
+//SDL audio thread
+//export Chip8AudioCallback
+func Chip8AudioCallback(userdata unsafe.Pointer, stream *C.Uint8, length C.int) {
+	n := int(length) / 2
+	buf := (*[1 << 28]int16)(unsafe.Pointer(stream))[:n:n]
+	if generator == nil {
+		for i := range buf {
+			buf[i] = 0
+		}
+		return
+	}
+	generator.Render(buf)
+}
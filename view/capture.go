@@ -0,0 +1,48 @@
+package view
+
+import (
+	"chip8emu/capture"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+//capturePath get the path for a screenshot or recording of a ROM, named by the
+//time it was taken and stored alongside the ROM unless a state directory is given
+func capturePath(romFile, stateDir, ext string) string {
+	dir := stateDir
+	if dir == "" {
+		dir = filepath.Dir(romFile)
+	}
+	name := fmt.Sprintf("%v-%v%v", filepath.Base(romFile), time.Now().Format("20060102-150405"), ext)
+	return filepath.Join(dir, name)
+}
+
+func (r *SDLInput) screenshot() {
+	path := capturePath(r.romFile, r.stateDir, ".png")
+	if err := capture.SavePNG(path, r.cpu, r.capture); err != nil {
+		fmt.Printf("Unable to save screenshot: %v\n", err)
+		return
+	}
+	fmt.Printf("Saved screenshot to %v\n", path)
+}
+
+func (r *SDLInput) startRecording() {
+	path := capturePath(r.romFile, r.stateDir, ".gif")
+	r.recorder = capture.NewRecorder(r.cpu, path, r.capture)
+	fmt.Printf("Recording to %v\n", path)
+}
+
+//stopRecording writes the recording started by the hotkey, if there is one
+func (r *SDLInput) stopRecording() {
+	if r.recorder == nil {
+		return
+	}
+	rec := r.recorder
+	r.recorder = nil
+	if err := rec.Close(); err != nil {
+		fmt.Printf("Unable to save recording: %v\n", err)
+		return
+	}
+	fmt.Printf("Saved %d frames to %v\n", rec.Frames(), rec.Path())
+}
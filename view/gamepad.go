@@ -0,0 +1,119 @@
+package view
+
+import (
+	"chip8emu/core"
+	"chip8emu/keymap"
+	"fmt"
+
+	"gopkg.in/veandco/go-sdl2.v0/sdl"
+)
+
+//gamepads the game controllers plugged in, read through SDL's game controller
+//API so buttons have the same names on every pad. SDL reports controllers already
+//plugged in at start up as added, so they're opened the same way as those
+//plugged in later.
+type gamepads struct {
+	cpu         *core.Chip8
+	keys        *keymap.Keymap
+	controllers map[sdl.JoystickID]*gamepad
+}
+
+//gamepad an open controller and the control each of its axes is pushed to
+type gamepad struct {
+	controller *sdl.GameController
+	axes       map[uint8]string
+}
+
+func newGamepads(keys *keymap.Keymap) *gamepads {
+	return &gamepads{keys: keys, controllers: map[sdl.JoystickID]*gamepad{}}
+}
+
+//handleEvent processes a controller event, returning false for other events
+func (p *gamepads) handleEvent(event sdl.Event) bool {
+	switch e := event.(type) {
+	case *sdl.ControllerDeviceEvent:
+		switch e.Type {
+		case sdl.CONTROLLERDEVICEADDED:
+			//Which is the device index when added, the instance ID after
+			p.open(int(e.Which))
+		case sdl.CONTROLLERDEVICEREMOVED:
+			p.close(e.Which)
+		}
+	case *sdl.ControllerButtonEvent:
+		if key, exists := p.keys.Button(sdl.GameControllerGetStringForButton(sdl.GameControllerButton(e.Button))); exists {
+			if e.Type == sdl.CONTROLLERBUTTONDOWN {
+				p.cpu.SetKey(key)
+			} else {
+				p.cpu.ClrKey(key)
+			}
+		}
+	case *sdl.ControllerAxisEvent:
+		if pad, exists := p.controllers[e.Which]; exists {
+			axis := sdl.GameControllerGetStringForAxis(sdl.GameControllerAxis(e.Axis))
+			pad.move(p, e.Axis, p.keys.AxisControl(axis, e.Value))
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func (p *gamepads) open(index int) {
+	controller := sdl.GameControllerOpen(index)
+	if controller == nil {
+		fmt.Printf("Unable to open game controller %d: %v\n", index, sdl.GetError())
+		return
+	}
+	id := controller.GetJoystick().InstanceID()
+	if _, exists := p.controllers[id]; exists {
+		//Already open, in case SDL reports a controller plugged in while
+		//initialising twice
+		controller.Close()
+		return
+	}
+	p.controllers[id] = &gamepad{controller: controller, axes: map[uint8]string{}}
+	fmt.Printf("Game controller connected: %s\n", controller.Name())
+}
+
+//close closes an unplugged controller, releasing any keys its sticks held.
+//Buttons held while unplugging are released by SDL first.
+func (p *gamepads) close(id sdl.JoystickID) {
+	pad, exists := p.controllers[id]
+	if !exists {
+		return
+	}
+	for axis := range pad.axes {
+		pad.move(p, axis, "")
+	}
+	fmt.Printf("Game controller disconnected: %s\n", pad.controller.Name())
+	pad.controller.Close()
+	delete(p.controllers, id)
+}
+
+//closeAll closes the controllers on shutdown
+func (p *gamepads) closeAll() {
+	for id, pad := range p.controllers {
+		pad.controller.Close()
+		delete(p.controllers, id)
+	}
+}
+
+//move notes an axis now pushed to a control, "" when centred, releasing the key
+//of the control it was pushed to before and pressing the new one's
+func (pad *gamepad) move(p *gamepads, axis uint8, control string) {
+	previous := pad.axes[axis]
+	if control == previous {
+		return
+	}
+	if key, exists := p.keys.Button(previous); exists {
+		p.cpu.ClrKey(key)
+	}
+	if key, exists := p.keys.Button(control); exists {
+		p.cpu.SetKey(key)
+	}
+	if control == "" {
+		delete(pad.axes, axis)
+	} else {
+		pad.axes[axis] = control
+	}
+}
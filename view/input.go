@@ -0,0 +1,167 @@
+package view
+
+import (
+	"chip8emu/capture"
+	"chip8emu/core"
+	"chip8emu/keymap"
+	"fmt"
+
+	"gopkg.in/veandco/go-sdl2.v0/sdl"
+)
+
+//keyBindings a keymap resolved to SDL key codes
+type keyBindings struct {
+	keys    map[sdl.Keycode]uint8
+	actions map[sdl.Keycode]keymap.Action
+}
+
+//newKeyBindings resolves the key names of a keymap to SDL key codes
+func newKeyBindings(k *keymap.Keymap) (keyBindings, error) {
+	b := keyBindings{keys: map[sdl.Keycode]uint8{}, actions: map[sdl.Keycode]keymap.Action{}}
+	code := func(name string) (sdl.Keycode, error) {
+		c := sdl.GetKeyFromName(name)
+		if c == sdl.K_UNKNOWN {
+			return c, fmt.Errorf("unknown key %q in keymap", name)
+		}
+		return c, nil
+	}
+	for name, key := range k.Keys {
+		c, err := code(name)
+		if err != nil {
+			return b, err
+		}
+		b.keys[c] = key
+	}
+	for name, a := range k.Actions {
+		c, err := code(name)
+		if err != nil {
+			return b, err
+		}
+		b.actions[c] = a
+	}
+	return b, nil
+}
+
+//SDLInput reads the keypad and the emulator hotkeys from the SDL window's keyboard
+//and game controller events, as bound by the keymap
+type SDLInput struct {
+	cpu       *core.Chip8
+	bindings  keyBindings
+	pads      *gamepads
+	display   *SDLDisplayRenderer
+	romFile   string
+	stateDir  string
+	stateSlot int
+	capture   capture.Options
+	recorder  *capture.Recorder
+}
+
+//HandleInput processes all the pending SDL events, updating the keypad and
+//handling the hotkeys. Handling only one would leave a burst of events, e.g. mouse
+//motion or a moving stick, queued for frames.
+func (r *SDLInput) HandleInput() {
+
+	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
+		if !r.pads.handleEvent(event) {
+			r.handleKeyPress(event)
+		}
+	}
+
+}
+
+func (r *SDLInput) handleKeyPress(event sdl.Event) {
+	r.doKeyPress(event)
+}
+
+func (r *SDLInput) doKeyPress(event sdl.Event) {
+
+	switch e := event.(type) {
+	case *sdl.KeyDownEvent:
+		chipKey, exists := r.bindings.keys[e.Keysym.Sym]
+		if exists {
+			r.cpu.SetKey(chipKey)
+		}
+
+		if action, exists := r.bindings.actions[e.Keysym.Sym]; exists {
+			r.doAction(action)
+		}
+
+		break
+	case *sdl.KeyUpEvent:
+		chipKey, exists := r.bindings.keys[e.Keysym.Sym]
+		if exists {
+			r.cpu.ClrKey(chipKey)
+		}
+		break
+	case *sdl.QuitEvent:
+		r.display.Alive = false
+	}
+
+}
+
+//doAction carries out an emulator action bound to a key
+func (r *SDLInput) doAction(action keymap.Action) {
+	switch action {
+	case keymap.Pause:
+		r.cpu.MM.Activate()
+	case keymap.Continue:
+		r.cpu.MM.Continue()
+	case keymap.Step:
+		r.cpu.MM.Activate()
+		r.cpu.MM.SetRunStep()
+	case keymap.Reset:
+		r.cpu.Reset()
+		fmt.Println("Reset")
+	case keymap.SaveState:
+		r.saveState()
+	case keymap.NextSlot:
+		r.stateSlot = (r.stateSlot + 1) % stateSlots
+		fmt.Printf("Save state slot %d\n", r.stateSlot)
+	case keymap.LoadState:
+		r.loadState()
+	case keymap.Record:
+		if r.recorder == nil {
+			r.startRecording()
+		} else {
+			r.stopRecording()
+		}
+	case keymap.Screenshot:
+		r.screenshot()
+	case keymap.SpeedUp:
+		r.cpu.SetCyclesPerFrame(r.cpu.CyclesPerFrame + speedStep(r.cpu.CyclesPerFrame))
+		fmt.Printf("CPU speed: %dHz\n", r.cpu.ClockHz())
+	case keymap.SpeedDown:
+		r.cpu.SetCyclesPerFrame(r.cpu.CyclesPerFrame - speedStep(r.cpu.CyclesPerFrame))
+		fmt.Printf("CPU speed: %dHz\n", r.cpu.ClockHz())
+	case keymap.Turbo:
+		r.cpu.Turbo = !r.cpu.Turbo
+		fmt.Printf("Turbo: %v\n", r.cpu.Turbo)
+	}
+}
+
+func (r *SDLInput) saveState() {
+	path := stateSlotPath(r.romFile, r.stateDir, r.stateSlot)
+	if err := saveStateSlot(r.cpu, path); err != nil {
+		fmt.Printf("Unable to save state: %v\n", err)
+		return
+	}
+	fmt.Printf("Saved state to %v\n", path)
+}
+
+func (r *SDLInput) loadState() {
+	path := stateSlotPath(r.romFile, r.stateDir, r.stateSlot)
+	if err := loadStateSlot(r.cpu, path); err != nil {
+		fmt.Printf("Unable to load state: %v\n", err)
+		return
+	}
+	fmt.Printf("Loaded state from %v\n", path)
+}
+
+//speedStep the change in instructions per frame for the speed hotkeys, a quarter
+//of the current speed so the keys remain useful for both slow and fast ROMs.
+func speedStep(cyclesPerFrame int) int {
+	if step := cyclesPerFrame / 4; step > 1 {
+		return step
+	}
+	return 1
+}
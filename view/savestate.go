@@ -0,0 +1,46 @@
+package view
+
+import (
+	"chip8emu/core"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+//Number of save state slots, selected with the slot hotkey
+const stateSlots = 10
+
+//stateSlotPath get the path of the save state file for a ROM and slot, slots are
+//stored alongside the ROM unless a state directory is given.
+func stateSlotPath(romFile, stateDir string, slot int) string {
+	dir := stateDir
+	if dir == "" {
+		dir = filepath.Dir(romFile)
+	}
+	return filepath.Join(dir, fmt.Sprintf("%v.state%d", filepath.Base(romFile), slot))
+}
+
+//saveStateSlot saves the machine state to the given slot file
+func saveStateSlot(cpu *core.Chip8, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := cpu.SaveState(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+//loadStateSlot restores the machine state from the given slot file
+func loadStateSlot(cpu *core.Chip8, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return cpu.LoadState(f)
+}